@@ -6,6 +6,9 @@ type BrokerMsg struct {
 	Pattern string
 	Channel string
 	Payload []byte
+	// Dropped is the number of messages the broker discarded for this
+	// subscription since the previous one; hubs send stream.gap for them.
+	Dropped int
 }
 
 type Subscription interface {
@@ -17,3 +20,17 @@ type Broker interface {
 	Subscribe(ctx context.Context, patterns ...string) (Subscription, error)
 	Publish(ctx context.Context, channel string, payload []byte) error
 }
+
+// BatchBroker is implemented by brokers that can publish several messages in
+// one round-trip. PublishBatch returns one error per message, nil on success.
+type BatchBroker interface {
+	PublishBatch(ctx context.Context, msgs []BrokerMsg) []error
+}
+
+// Sequencer publishes payloads framed with the next sequence number of their
+// channel (see SequencePayload). Taking the number and publishing must be one
+// atomic step, so numbers are neither skipped by failed publishes nor
+// delivered out of order.
+type Sequencer interface {
+	PublishSequenced(ctx context.Context, channel string, payload []byte) error
+}
@@ -0,0 +1,183 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// BrokerMiddleware wraps a Broker to add behavior at the broker boundary.
+type BrokerMiddleware func(Broker) Broker
+
+// ChainBroker wraps b with mws. mws[0] is the outermost layer: it sees
+// publishes first and subscription messages last.
+func ChainBroker(b Broker, mws ...BrokerMiddleware) Broker {
+	for i := len(mws) - 1; i >= 0; i-- {
+		b = mws[i](b)
+	}
+	return b
+}
+
+type PublishFunc func(ctx context.Context, channel string, payload []byte) error
+
+// InterceptBroker builds a middleware from a Publish wrapper and a function
+// applied to every message delivered by subscriptions; a message is dropped
+// when receive returns false, and counted in BrokerMsg.Dropped of the next
+// delivered one so hubs send stream.gap. Either may be nil. The wrapped broker does not
+// implement BatchBroker, so batches are published one message at a time.
+func InterceptBroker(publish func(next PublishFunc) PublishFunc, receive func(BrokerMsg) (BrokerMsg, bool)) BrokerMiddleware {
+	return func(next Broker) Broker {
+		b := &interceptedBroker{next: next, publish: next.Publish, receive: receive}
+		if publish != nil {
+			b.publish = publish(next.Publish)
+		}
+		return b
+	}
+}
+
+type interceptedBroker struct {
+	next    Broker
+	publish PublishFunc
+	receive func(BrokerMsg) (BrokerMsg, bool)
+}
+
+func (b *interceptedBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	return b.publish(ctx, channel, payload)
+}
+
+func (b *interceptedBroker) Subscribe(ctx context.Context, patterns ...string) (Subscription, error) {
+	sub, err := b.next.Subscribe(ctx, patterns...)
+	if err != nil || b.receive == nil {
+		return sub, err
+	}
+
+	s := &interceptedSubscription{
+		next: sub,
+		out:  make(chan BrokerMsg),
+		done: make(chan struct{}),
+	}
+	go s.run(b.receive)
+	return s, nil
+}
+
+type interceptedSubscription struct {
+	next Subscription
+	out  chan BrokerMsg
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *interceptedSubscription) run(receive func(BrokerMsg) (BrokerMsg, bool)) {
+	defer close(s.out)
+
+	in := s.next.Channel()
+	dropped := 0
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg, ok := <-in:
+			if !ok {
+				return
+			}
+			filtered, ok := receive(msg)
+			if !ok {
+				dropped += msg.Dropped + 1
+				continue
+			}
+			filtered.Dropped += dropped
+			select {
+			case s.out <- filtered:
+				dropped = 0
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+func (s *interceptedSubscription) Channel() <-chan BrokerMsg { return s.out }
+
+func (s *interceptedSubscription) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.next.Close()
+}
+
+// BrokerLogger logs publishes and received messages at debug level and
+// failed publishes at error level.
+func BrokerLogger(logger *slog.Logger) BrokerMiddleware {
+	return InterceptBroker(
+		func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, channel string, payload []byte) error {
+				start := time.Now()
+				err := next(ctx, channel, payload)
+				if err != nil {
+					logger.ErrorContext(ctx, "broker publish failed",
+						"channel", channel, "bytes", len(payload), "error", err)
+					return err
+				}
+				logger.DebugContext(ctx, "broker publish",
+					"channel", channel, "bytes", len(payload), "duration", time.Since(start))
+				return nil
+			}
+		},
+		func(msg BrokerMsg) (BrokerMsg, bool) {
+			logger.Debug("broker message",
+				"pattern", msg.Pattern, "channel", msg.Channel, "bytes", len(msg.Payload))
+			return msg, true
+		},
+	)
+}
+
+type BrokerMetricsHooks struct {
+	OnPublish func(channel string, bytes int, latency time.Duration, err error)
+	OnReceive func(channel string, bytes int)
+}
+
+// BrokerMetrics reports publish latency and message sizes through hooks.
+func BrokerMetrics(hooks BrokerMetricsHooks) BrokerMiddleware {
+	var publish func(PublishFunc) PublishFunc
+	if hooks.OnPublish != nil {
+		publish = func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, channel string, payload []byte) error {
+				start := time.Now()
+				err := next(ctx, channel, payload)
+				hooks.OnPublish(channel, len(payload), time.Since(start), err)
+				return err
+			}
+		}
+	}
+
+	var receive func(BrokerMsg) (BrokerMsg, bool)
+	if hooks.OnReceive != nil {
+		receive = func(msg BrokerMsg) (BrokerMsg, bool) {
+			hooks.OnReceive(msg.Channel, len(msg.Payload))
+			return msg, true
+		}
+	}
+
+	return InterceptBroker(publish, receive)
+}
+
+// BrokerMaxPayload rejects publishes larger than maxBytes with
+// ErrPayloadTooLarge and drops larger messages received from subscriptions.
+func BrokerMaxPayload(maxBytes int) BrokerMiddleware {
+	return InterceptBroker(
+		func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, channel string, payload []byte) error {
+				if len(payload) > maxBytes {
+					return fmt.Errorf("%w: %d bytes on %s, limit %d", ErrPayloadTooLarge, len(payload), channel, maxBytes)
+				}
+				return next(ctx, channel, payload)
+			}
+		},
+		func(msg BrokerMsg) (BrokerMsg, bool) {
+			return msg, len(msg.Payload) <= maxBytes
+		},
+	)
+}
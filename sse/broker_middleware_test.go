@@ -0,0 +1,183 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func recvMsg(t *testing.T, sub Subscription) BrokerMsg {
+	t.Helper()
+	select {
+	case msg := <-sub.Channel():
+		return msg
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout waiting for message")
+	}
+	return BrokerMsg{}
+}
+
+func tagMiddleware(tag string, order *[]string) BrokerMiddleware {
+	return InterceptBroker(
+		func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, channel string, payload []byte) error {
+				*order = append(*order, "publish "+tag)
+				return next(ctx, channel, append(payload, tag...))
+			}
+		},
+		func(msg BrokerMsg) (BrokerMsg, bool) {
+			msg.Payload = append(msg.Payload, tag...)
+			return msg, true
+		},
+	)
+}
+
+func TestChainBrokerOrder(t *testing.T) {
+	var order []string
+	broker := ChainBroker(newTestBroker(), tagMiddleware("a", &order), tagMiddleware("b", &order))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("x")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	if strings.Join(order, ",") != "publish a,publish b" {
+		t.Fatalf("unexpected publish order: %v", order)
+	}
+	// Published as "xab", then received through b before a.
+	if msg := recvMsg(t, sub); string(msg.Payload) != "xabba" {
+		t.Fatalf("unexpected payload: %q", msg.Payload)
+	}
+}
+
+func TestInterceptBrokerDropsAndCloses(t *testing.T) {
+	broker := ChainBroker(newTestBroker(), InterceptBroker(nil, func(msg BrokerMsg) (BrokerMsg, bool) {
+		return msg, string(msg.Payload) != "drop"
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	_ = broker.Publish(context.Background(), "scope:1:students", []byte("drop"))
+	_ = broker.Publish(context.Background(), "scope:1:students", []byte("keep"))
+
+	if msg := recvMsg(t, sub); string(msg.Payload) != "keep" || msg.Dropped != 1 {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	if err := sub.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	select {
+	case _, ok := <-sub.Channel():
+		if ok {
+			t.Fatal("expected channel to be closed")
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout waiting for channel close")
+	}
+}
+
+func TestBrokerMaxPayload(t *testing.T) {
+	inner := newTestBroker()
+	broker := ChainBroker(inner, BrokerMaxPayload(4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("too long")); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("expected ErrPayloadTooLarge, got %v", err)
+	}
+
+	// Oversized messages published around the middleware are dropped on receive.
+	_ = inner.Publish(context.Background(), "scope:1:students", []byte("too long"))
+	_ = inner.Publish(context.Background(), "scope:1:students", []byte("ok"))
+	if msg := recvMsg(t, sub); string(msg.Payload) != "ok" || msg.Dropped != 1 {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestInterceptBrokerCarriesDropsAcrossLayers(t *testing.T) {
+	filter := func(drop string) BrokerMiddleware {
+		return InterceptBroker(nil, func(msg BrokerMsg) (BrokerMsg, bool) {
+			return msg, string(msg.Payload) != drop
+		})
+	}
+	broker := ChainBroker(newTestBroker(), filter("b"), filter("a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	for _, p := range []string{"a", "b", "c"} {
+		_ = broker.Publish(context.Background(), "scope:1:students", []byte(p))
+	}
+
+	if msg := recvMsg(t, sub); string(msg.Payload) != "c" || msg.Dropped != 2 {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestBrokerMetrics(t *testing.T) {
+	var published, received int
+	broker := ChainBroker(newTestBroker(), BrokerMetrics(BrokerMetricsHooks{
+		OnPublish: func(channel string, n int, latency time.Duration, err error) {
+			if channel != "scope:1:students" || err != nil || latency < 0 {
+				t.Errorf("unexpected publish metrics: %s %v %v", channel, latency, err)
+			}
+			published += n
+		},
+		OnReceive: func(_ string, n int) { received += n },
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	_ = broker.Publish(context.Background(), "scope:1:students", []byte("hello"))
+	recvMsg(t, sub)
+
+	if published != 5 || received != 5 {
+		t.Fatalf("unexpected byte counts: published=%d received=%d", published, received)
+	}
+}
+
+func TestBrokerLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	broker := ChainBroker(newTestBroker(), BrokerLogger(logger))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := broker.Publish(canceled, "scope:1:students", []byte("x")); err == nil {
+		t.Fatal("expected publish error")
+	}
+	if !strings.Contains(buf.String(), "broker publish failed") || !strings.Contains(buf.String(), "channel=scope:1:students") {
+		t.Fatalf("unexpected log output: %s", buf.String())
+	}
+}
@@ -0,0 +1,65 @@
+package cbor
+
+import (
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// encMode keeps sub-second precision for ExpiresAt; the default encodes
+// time as whole Unix seconds.
+var encMode, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+
+type envelope struct {
+	EventType string    `cbor:"event_type"`
+	Data      []byte    `cbor:"data,omitempty"`
+	ExpiresAt time.Time `cbor:"expires_at,omitempty"`
+	ID        string    `cbor:"id,omitempty"`
+	Sequence  uint64    `cbor:"seq,omitempty"`
+
+	Source          string    `cbor:"source,omitempty"`
+	Subject         string    `cbor:"subject,omitempty"`
+	Time            time.Time `cbor:"time,omitempty"`
+	DataContentType string    `cbor:"datacontenttype,omitempty"`
+}
+
+type Codec struct{}
+
+func NewCodec() Codec {
+	return Codec{}
+}
+
+func (Codec) Name() string { return "cbor" }
+
+func (Codec) Marshal(evt sse.Event) ([]byte, error) {
+	return encMode.Marshal(envelope{
+		EventType: evt.EventType,
+		Data:      evt.Data,
+		ExpiresAt: evt.ExpiresAt,
+		ID:        evt.ID,
+		Sequence:  evt.Sequence,
+
+		Source:          evt.Source,
+		Subject:         evt.Subject,
+		Time:            evt.Time,
+		DataContentType: evt.DataContentType,
+	})
+}
+
+func (Codec) Unmarshal(raw []byte, evt *sse.Event) error {
+	var env envelope
+	if err := cbor.Unmarshal(raw, &env); err != nil {
+		return err
+	}
+	evt.EventType = env.EventType
+	evt.Data = env.Data
+	evt.ExpiresAt = env.ExpiresAt
+	evt.ID = env.ID
+	evt.Sequence = env.Sequence
+	evt.Source = env.Source
+	evt.Subject = env.Subject
+	evt.Time = env.Time
+	evt.DataContentType = env.DataContentType
+	return nil
+}
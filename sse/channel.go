@@ -0,0 +1,123 @@
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidChannel = errors.New("invalid channel")
+
+type ChannelValidationMode int
+
+const (
+	ChannelValidationOff ChannelValidationMode = iota
+	ChannelValidationWarn
+	ChannelValidationStrict
+)
+
+// ChannelName is a channel following the {scope}:{scope_id}:{topic} contract.
+type ChannelName struct {
+	Scope   string
+	ScopeID int64
+	Topic   string
+}
+
+func Channel(scope string, scopeID int64, topic string) ChannelName {
+	return ChannelName{Scope: scope, ScopeID: scopeID, Topic: topic}
+}
+
+// ChannelPattern is the pattern matching every topic of a scope, suitable
+// for ChannelRouter output.
+func ChannelPattern(scope string, scopeID int64) string {
+	return Channel(scope, scopeID, "*").String()
+}
+
+func (c ChannelName) String() string {
+	return c.Scope + ":" + strconv.FormatInt(c.ScopeID, 10) + ":" + c.Topic
+}
+
+func ParseChannel(channel string) (ChannelName, error) {
+	parts := strings.Split(channel, ":")
+	if len(parts) != 3 {
+		return ChannelName{}, fmt.Errorf("%w: %q must be {scope}:{scope_id}:{topic}", ErrInvalidChannel, channel)
+	}
+	for _, p := range parts {
+		if p == "" {
+			return ChannelName{}, fmt.Errorf("%w: %q has an empty segment", ErrInvalidChannel, channel)
+		}
+	}
+	if hasGlob(parts[0]) {
+		return ChannelName{}, fmt.Errorf("%w: %q scope cannot contain wildcards", ErrInvalidChannel, channel)
+	}
+	scopeID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ChannelName{}, fmt.Errorf("%w: %q scope id must be an integer", ErrInvalidChannel, channel)
+	}
+	c := ChannelName{Scope: parts[0], ScopeID: scopeID, Topic: parts[2]}
+	// "gym:01:students" would parse as scope 1 yet never match "gym:1:*".
+	if c.String() != channel {
+		return ChannelName{}, fmt.Errorf("%w: %q scope id is not canonical", ErrInvalidChannel, channel)
+	}
+	return c, nil
+}
+
+// ValidateChannel checks a channel used for publishing: it must follow the
+// naming contract and contain no wildcards.
+func ValidateChannel(channel string) error {
+	c, err := ParseChannel(channel)
+	if err != nil {
+		return err
+	}
+	if hasGlob(c.Topic) {
+		return fmt.Errorf("%w: %q cannot contain wildcards on publish", ErrInvalidChannel, channel)
+	}
+	return nil
+}
+
+// ValidateChannelPattern checks a subscription pattern returned by a
+// ChannelRouter: it must follow the naming contract and stay within scopeID.
+// Wildcards are only allowed in the topic.
+func ValidateChannelPattern(pattern string, scopeID int64) error {
+	c, err := ParseChannel(pattern)
+	if err != nil {
+		return err
+	}
+	if c.ScopeID != scopeID {
+		return fmt.Errorf("%w: %q crosses into scope %d", ErrInvalidChannel, pattern, c.ScopeID)
+	}
+	return nil
+}
+
+func validateRoutes(patterns []string, scopeID int64) error {
+	if len(patterns) == 0 {
+		return fmt.Errorf("%w: router returned no patterns", ErrInvalidChannel)
+	}
+	var errs []error
+	for _, p := range patterns {
+		if err := ValidateChannelPattern(p, scopeID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func hasGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[]")
+}
+
+// checkChannels applies mode to the result of validate. It returns the error
+// only in strict mode; in warn mode it is reported through onError.
+func checkChannels(mode ChannelValidationMode, err error, onError func(error)) error {
+	if err == nil || mode == ChannelValidationOff {
+		return nil
+	}
+	if mode == ChannelValidationStrict {
+		return err
+	}
+	if onError != nil {
+		onError(err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChannelBuilder(t *testing.T) {
+	if got := Channel("gym", 1, "students").String(); got != "gym:1:students" {
+		t.Fatalf("unexpected channel: %s", got)
+	}
+	if got := ChannelPattern("gym", 1); got != "gym:1:*" {
+		t.Fatalf("unexpected pattern: %s", got)
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	c, err := ParseChannel("org:42:plans")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c != Channel("org", 42, "plans") {
+		t.Fatalf("unexpected channel: %+v", c)
+	}
+
+	for _, bad := range []string{"", "global", "gym:1", "gym::students", "gym:x:students", "*:1:students", "gym:1:a:b", "gym:+1:students", "gym:01:students"} {
+		if _, err := ParseChannel(bad); !errors.Is(err, ErrInvalidChannel) {
+			t.Fatalf("expected ErrInvalidChannel for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestValidateChannelRejectsWildcards(t *testing.T) {
+	if err := ValidateChannel("gym:1:students"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateChannel("gym:1:*"); err == nil {
+		t.Fatal("expected error for wildcard on publish")
+	}
+}
+
+func TestValidateChannelPattern(t *testing.T) {
+	if err := ValidateChannelPattern("gym:1:*", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateChannelPattern("gym:2:*", 1); err == nil {
+		t.Fatal("expected error for cross-scope pattern")
+	}
+	if err := ValidateChannelPattern("gym:*:students", 1); err == nil {
+		t.Fatal("expected error for wildcard scope id")
+	}
+	if err := ValidateChannelPattern("*", 1); err == nil {
+		t.Fatal("expected error for global pattern")
+	}
+}
+
+func TestPublisherChannelValidationModes(t *testing.T) {
+	strict := NewPublisherWithOptions(newTestBroker(), PublisherOptions{ChannelValidation: ChannelValidationStrict})
+	if err := strict.PublishType(context.Background(), "global", "students.changed"); !errors.Is(err, ErrInvalidChannel) {
+		t.Fatalf("expected ErrInvalidChannel, got %v", err)
+	}
+
+	var reported error
+	warn := NewPublisherWithOptions(newTestBroker(), PublisherOptions{
+		ChannelValidation: ChannelValidationWarn,
+		OnError:           func(_ context.Context, err error) { reported = err },
+	})
+	if err := warn.PublishType(context.Background(), "global", "students.changed"); err != nil {
+		t.Fatalf("unexpected error in warn mode: %v", err)
+	}
+	if !errors.Is(reported, ErrInvalidChannel) {
+		t.Fatalf("expected warning to be reported, got %v", reported)
+	}
+}
+
+func TestSSEHandlerRejectsCrossScopeRoutesInStrictMode(t *testing.T) {
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router:            func(*Principal) []string { return []string{"scope:2:*"} },
+		ChannelValidation: ChannelValidationStrict,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
@@ -0,0 +1,254 @@
+// Package chaos wraps an sse.Broker with fault injection for resilience
+// tests. Every random decision comes from one RNG seeded by Options.Seed, so a
+// given seed and message sequence always produce the same faults.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+)
+
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Options holds fault probabilities in [0, 1]. Message faults apply to every
+// message delivered by subscriptions.
+type Options struct {
+	Seed int64
+
+	DropRate      float64
+	DuplicateRate float64
+	// ReorderRate holds a message back and delivers it after the next one.
+	ReorderRate float64
+	// DelayRate delays a message by a random duration up to MaxDelay.
+	DelayRate float64
+	MaxDelay  time.Duration
+
+	PublishErrorRate   float64
+	SubscribeErrorRate float64
+}
+
+type Broker struct {
+	next sse.Broker
+
+	mu            sync.Mutex
+	opts          Options
+	rng           *rand.Rand
+	failPublish   int
+	failSubscribe int
+	subs          map[*subscription]struct{}
+}
+
+func NewBroker(next sse.Broker, options Options) *Broker {
+	return &Broker{
+		next: next,
+		opts: options,
+		rng:  rand.New(rand.NewSource(options.Seed)),
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+// SetOptions replaces the fault probabilities. The RNG keeps its state, so
+// Seed is ignored.
+func (b *Broker) SetOptions(options Options) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.opts = options
+}
+
+// FailPublishes makes the next n Publish calls return ErrInjected.
+func (b *Broker) FailPublishes(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failPublish = n
+}
+
+// FailSubscribes makes the next n Subscribe calls return ErrInjected.
+func (b *Broker) FailSubscribes(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failSubscribe = n
+}
+
+// CloseSubscriptions abruptly closes every open subscription's channel, as a
+// dropped broker connection would, and returns how many were closed.
+func (b *Broker) CloseSubscriptions() int {
+	b.mu.Lock()
+	subs := make([]*subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		_ = sub.Close()
+	}
+	return len(subs)
+}
+
+func (b *Broker) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.mu.Lock()
+	fail := b.failPublish > 0 || b.chance(b.opts.PublishErrorRate)
+	if b.failPublish > 0 {
+		b.failPublish--
+	}
+	b.mu.Unlock()
+
+	if fail {
+		return ErrInjected
+	}
+	return b.next.Publish(ctx, channel, payload)
+}
+
+func (b *Broker) Subscribe(ctx context.Context, patterns ...string) (sse.Subscription, error) {
+	b.mu.Lock()
+	fail := b.failSubscribe > 0 || b.chance(b.opts.SubscribeErrorRate)
+	if b.failSubscribe > 0 {
+		b.failSubscribe--
+	}
+	b.mu.Unlock()
+
+	if fail {
+		return nil, ErrInjected
+	}
+
+	next, err := b.next.Subscribe(ctx, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &subscription{
+		broker: b,
+		next:   next,
+		out:    make(chan sse.BrokerMsg),
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go sub.run()
+	return sub, nil
+}
+
+type fault struct {
+	drop      bool
+	duplicate bool
+	reorder   bool
+	delay     time.Duration
+}
+
+func (b *Broker) decide() fault {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var f fault
+	f.drop = b.chance(b.opts.DropRate)
+	f.duplicate = b.chance(b.opts.DuplicateRate)
+	f.reorder = b.chance(b.opts.ReorderRate)
+	if b.chance(b.opts.DelayRate) && b.opts.MaxDelay > 0 {
+		f.delay = time.Duration(b.rng.Int63n(int64(b.opts.MaxDelay) + 1))
+	}
+	return f
+}
+
+// chance draws from the RNG even when rate is zero, so changing one rate
+// does not shift the decisions made for the others. Callers must hold b.mu.
+func (b *Broker) chance(rate float64) bool {
+	return b.rng.Float64() < rate
+}
+
+type subscription struct {
+	broker *Broker
+	next   sse.Subscription
+	out    chan sse.BrokerMsg
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *subscription) run() {
+	defer close(s.out)
+	defer func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+	}()
+
+	var held *sse.BrokerMsg
+	in := s.next.Channel()
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg, ok := <-in:
+			if !ok {
+				if held != nil {
+					s.send(*held)
+				}
+				return
+			}
+
+			f := s.broker.decide()
+			if f.drop {
+				continue
+			}
+			if f.delay > 0 && !s.sleep(f.delay) {
+				return
+			}
+			if f.reorder && held == nil {
+				held = &msg
+				continue
+			}
+
+			if !s.send(msg) {
+				return
+			}
+			if f.duplicate && !s.send(msg) {
+				return
+			}
+			if held != nil {
+				if !s.send(*held) {
+					return
+				}
+				held = nil
+			}
+		}
+	}
+}
+
+func (s *subscription) send(msg sse.BrokerMsg) bool {
+	select {
+	case s.out <- msg:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *subscription) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *subscription) Channel() <-chan sse.BrokerMsg { return s.out }
+
+func (s *subscription) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+	})
+	return s.next.Close()
+}
@@ -0,0 +1,254 @@
+package chaos
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/PabloPavan/eventrail/sse/memory"
+)
+
+func deliver(t *testing.T, broker *Broker, payloads ...string) []string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	for _, p := range payloads {
+		if err := broker.Publish(context.Background(), "scope:1:students", []byte(p)); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	var got []string
+	for {
+		select {
+		case msg := <-sub.Channel():
+			got = append(got, string(msg.Payload))
+		case <-time.After(100 * time.Millisecond):
+			return got
+		}
+	}
+}
+
+func TestBrokerReorderSwapsPairs(t *testing.T) {
+	broker := NewBroker(memory.NewBrokerInMemory(), Options{ReorderRate: 1})
+
+	got := deliver(t, broker, "a", "b", "c", "d")
+	if strings.Join(got, ",") != "b,a,d,c" {
+		t.Fatalf("unexpected order: %v", got)
+	}
+}
+
+func TestBrokerSameSeedSameFaults(t *testing.T) {
+	opts := Options{Seed: 42, DropRate: 0.3, DuplicateRate: 0.2, ReorderRate: 0.2}
+	payloads := make([]string, 20)
+	for i := range payloads {
+		payloads[i] = fmt.Sprint(i)
+	}
+
+	first := deliver(t, NewBroker(memory.NewBrokerInMemory(), opts), payloads...)
+	second := deliver(t, NewBroker(memory.NewBrokerInMemory(), opts), payloads...)
+
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Fatalf("expected identical deliveries, got %v and %v", first, second)
+	}
+	if strings.Join(first, ",") == strings.Join(payloads, ",") {
+		t.Fatal("expected faults to alter the delivery")
+	}
+}
+
+func TestBrokerDropAndDuplicate(t *testing.T) {
+	broker := NewBroker(memory.NewBrokerInMemory(), Options{DuplicateRate: 1})
+	if got := deliver(t, broker, "a"); strings.Join(got, ",") != "a,a" {
+		t.Fatalf("expected duplicate, got %v", got)
+	}
+
+	broker.SetOptions(Options{DropRate: 1})
+	if got := deliver(t, broker, "a", "b"); len(got) != 0 {
+		t.Fatalf("expected all messages dropped, got %v", got)
+	}
+}
+
+func TestBrokerDelay(t *testing.T) {
+	broker := NewBroker(memory.NewBrokerInMemory(), Options{DelayRate: 1, MaxDelay: 30 * time.Millisecond})
+
+	start := time.Now()
+	got := deliver(t, broker, "a")
+	if len(got) != 1 || got[0] != "a" {
+		t.Fatalf("unexpected delivery: %v", got)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("delay exceeded MaxDelay")
+	}
+}
+
+func TestBrokerInjectedErrors(t *testing.T) {
+	broker := NewBroker(memory.NewBrokerInMemory(), Options{})
+
+	broker.FailPublishes(1)
+	if err := broker.Publish(context.Background(), "scope:1:students", nil); !errors.Is(err, ErrInjected) {
+		t.Fatalf("expected ErrInjected, got %v", err)
+	}
+	if err := broker.Publish(context.Background(), "scope:1:students", nil); err != nil {
+		t.Fatalf("expected second publish to succeed, got %v", err)
+	}
+
+	broker.FailSubscribes(1)
+	if _, err := broker.Subscribe(context.Background(), "scope:1:*"); !errors.Is(err, ErrInjected) {
+		t.Fatalf("expected ErrInjected, got %v", err)
+	}
+
+	broker.SetOptions(Options{PublishErrorRate: 1})
+	if err := broker.Publish(context.Background(), "scope:1:students", nil); !errors.Is(err, ErrInjected) {
+		t.Fatalf("expected ErrInjected, got %v", err)
+	}
+}
+
+func TestBrokerCloseSubscriptions(t *testing.T) {
+	broker := NewBroker(memory.NewBrokerInMemory(), Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subs := make([]sse.Subscription, 2)
+	for i := range subs {
+		sub, err := broker.Subscribe(ctx, "scope:1:*")
+		if err != nil {
+			t.Fatalf("subscribe failed: %v", err)
+		}
+		subs[i] = sub
+	}
+
+	if n := broker.CloseSubscriptions(); n != 2 {
+		t.Fatalf("expected 2 subscriptions closed, got %d", n)
+	}
+	for _, sub := range subs {
+		select {
+		case _, ok := <-sub.Channel():
+			if ok {
+				t.Fatal("expected channel to be closed")
+			}
+		case <-time.After(200 * time.Millisecond):
+			t.Fatal("timeout waiting for channel close")
+		}
+	}
+}
+
+func TestBrokerForgetsCancelledSubscriptions(t *testing.T) {
+	broker := NewBroker(memory.NewBrokerInMemory(), Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := broker.Subscribe(ctx, "scope:1:*"); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		broker.mu.Lock()
+		n := len(broker.subs)
+		broker.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected cancelled subscription to be forgotten, %d left", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type resolverFunc func(*http.Request) (*sse.Principal, error)
+
+func (f resolverFunc) Resolve(r *http.Request) (*sse.Principal, error) { return f(r) }
+
+func TestHubRecoversFromClosedSubscriptions(t *testing.T) {
+	broker := NewBroker(memory.NewBrokerInMemory(), Options{})
+
+	server, err := sse.NewServer(broker, sse.Options{
+		Resolver: resolverFunc(func(*http.Request) (*sse.Principal, error) {
+			return &sse.Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router:              func(*sse.Principal) []string { return []string{"scope:1:*"} },
+		ResubscribeInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		broker.mu.Lock()
+		n := len(broker.subs)
+		broker.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hub never subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Fail the first resubscribe so the hub has to retry.
+	broker.FailSubscribes(1)
+	broker.CloseSubscriptions()
+
+	for {
+		if err := server.Publisher().PublishEvent(context.Background(), "scope:1:students", sse.Event{
+			EventType: "students.changed",
+			Data:      []byte(`{"id":1}`),
+		}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+
+		wait := time.After(50 * time.Millisecond)
+	drain:
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatal("stream closed")
+				}
+				if line == "event: students.changed" {
+					return
+				}
+			case <-wait:
+				break drain
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not resubscribe after the subscription closed")
+		}
+	}
+}
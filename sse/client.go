@@ -0,0 +1,162 @@
+package sse
+
+import (
+	"sync"
+	"time"
+)
+
+type queuedMsg struct {
+	// id is the SSE id field, "{channel}:{sequence}" for numbered events.
+	id         string
+	payload    []byte
+	key        string
+	enqueuedAt time.Time
+	expiresAt  time.Time
+}
+
+func (m queuedMsg) expired(now time.Time) bool {
+	return !m.expiresAt.IsZero() && !now.Before(m.expiresAt)
+}
+
+type client struct {
+	mu      sync.Mutex
+	buf     []queuedMsg
+	head    int
+	size    int
+	dropped int
+	closed  bool
+	reason  DisconnectReason
+
+	info ConnInfo
+
+	notify chan struct{}
+	done   chan struct{}
+}
+
+func newClient(buf int) *client {
+	if buf < 1 {
+		buf = 1
+	}
+	return &client{
+		buf:    make([]queuedMsg, buf),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// enqueue stores msg according to policy. It reports whether msg was
+// accepted and whether another message had to be discarded to make room.
+func (c *client) enqueue(msg queuedMsg, policy BackpressurePolicy) (accepted, discarded bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false, false
+	}
+
+	if policy == BackpressureCoalesce && msg.key != "" {
+		for i := 0; i < c.size; i++ {
+			idx := (c.head + i) % len(c.buf)
+			if c.buf[idx].key == msg.key {
+				msg.enqueuedAt = c.buf[idx].enqueuedAt
+				c.buf[idx] = msg
+				c.signal()
+				return true, false
+			}
+		}
+	}
+
+	if c.size == len(c.buf) {
+		switch policy {
+		case BackpressureDropOldest, BackpressureCoalesce:
+			c.buf[c.head] = queuedMsg{}
+			c.head = (c.head + 1) % len(c.buf)
+			c.size--
+			c.dropped++
+			discarded = true
+		default:
+			c.dropped++
+			return false, false
+		}
+	}
+
+	c.buf[(c.head+c.size)%len(c.buf)] = msg
+	c.size++
+	c.signal()
+	return true, discarded
+}
+
+// markGap records messages lost before reaching the client.
+func (c *client) markGap(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.dropped += n
+	c.signal()
+}
+
+// next pops the oldest queued message. dropped is the number of messages
+// discarded since the previous call, so the caller can report the gap before
+// writing msg.
+func (c *client) next() (msg queuedMsg, dropped int, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dropped = c.dropped
+	c.dropped = 0
+
+	if c.size == 0 {
+		return queuedMsg{}, dropped, false
+	}
+
+	msg = c.buf[c.head]
+	c.buf[c.head] = queuedMsg{}
+	c.head = (c.head + 1) % len(c.buf)
+	c.size--
+	return msg, dropped, true
+}
+
+// lag is the age of the oldest queued message, or zero when the queue is
+// empty.
+func (c *client) lag(now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size == 0 || c.buf[c.head].enqueuedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(c.buf[c.head].enqueuedAt)
+}
+
+func (c *client) close() {
+	c.closeWith("")
+}
+
+// closeWith closes the client, recording why the hub removed it.
+func (c *client) closeWith(reason DisconnectReason) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.reason = reason
+	close(c.done)
+}
+
+func (c *client) closeReason() DisconnectReason {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reason
+}
+
+func (c *client) signal() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,92 @@
+package sse
+
+import "testing"
+
+func TestClientDropRejectsNewest(t *testing.T) {
+	c := newClient(2)
+
+	for _, p := range []string{"a", "b"} {
+		if accepted, _ := c.enqueue(queuedMsg{payload: []byte(p)}, BackpressureDrop); !accepted {
+			t.Fatalf("expected %s to be accepted", p)
+		}
+	}
+	if accepted, _ := c.enqueue(queuedMsg{payload: []byte("c")}, BackpressureDrop); accepted {
+		t.Fatal("expected newest message to be rejected")
+	}
+
+	msg, dropped, ok := c.next()
+	if !ok || string(msg.payload) != "a" {
+		t.Fatalf("unexpected message: %q", msg.payload)
+	}
+	if dropped != 1 {
+		t.Fatalf("unexpected dropped count: %d", dropped)
+	}
+}
+
+func TestClientDropOldestKeepsNewest(t *testing.T) {
+	c := newClient(2)
+
+	for _, p := range []string{"a", "b", "c"} {
+		if accepted, _ := c.enqueue(queuedMsg{payload: []byte(p)}, BackpressureDropOldest); !accepted {
+			t.Fatalf("expected %s to be accepted", p)
+		}
+	}
+
+	msg, dropped, ok := c.next()
+	if !ok || string(msg.payload) != "b" {
+		t.Fatalf("unexpected message: %q", msg.payload)
+	}
+	if dropped != 1 {
+		t.Fatalf("unexpected dropped count: %d", dropped)
+	}
+
+	msg, dropped, ok = c.next()
+	if !ok || string(msg.payload) != "c" {
+		t.Fatalf("unexpected message: %q", msg.payload)
+	}
+	if dropped != 0 {
+		t.Fatalf("expected dropped count to reset, got %d", dropped)
+	}
+
+	if _, _, ok := c.next(); ok {
+		t.Fatal("expected queue to be empty")
+	}
+}
+
+func TestClientCoalesceReplacesSameKey(t *testing.T) {
+	c := newClient(4)
+
+	c.enqueue(queuedMsg{payload: []byte("a1"), key: "a"}, BackpressureCoalesce)
+	c.enqueue(queuedMsg{payload: []byte("b1"), key: "b"}, BackpressureCoalesce)
+	c.enqueue(queuedMsg{payload: []byte("a2"), key: "a"}, BackpressureCoalesce)
+
+	var got []string
+	for {
+		msg, dropped, ok := c.next()
+		if dropped != 0 {
+			t.Fatalf("unexpected dropped count: %d", dropped)
+		}
+		if !ok {
+			break
+		}
+		got = append(got, string(msg.payload))
+	}
+	if len(got) != 2 || got[0] != "a2" || got[1] != "b1" {
+		t.Fatalf("unexpected messages: %v", got)
+	}
+}
+
+func TestClientEnqueueAfterClose(t *testing.T) {
+	c := newClient(1)
+	c.close()
+	c.close()
+
+	if accepted, _ := c.enqueue(queuedMsg{payload: []byte("a")}, BackpressureDropOldest); accepted {
+		t.Fatal("expected closed client to reject messages")
+	}
+	select {
+	case <-c.done:
+	default:
+		t.Fatal("expected done to be closed")
+	}
+}
@@ -0,0 +1,91 @@
+package sse
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCloudEventsCodecRoundTrip(t *testing.T) {
+	codec := CloudEventsCodec{Source: "/students-api"}
+	ts := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	raw, err := codec.Marshal(Event{
+		EventType: "students.changed",
+		Subject:   "student/123",
+		Time:      ts,
+		Data:      json.RawMessage(`{"id":123}`),
+	})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if fields["specversion"] != "1.0" || fields["type"] != "students.changed" || fields["source"] != "/students-api" {
+		t.Fatalf("unexpected attributes: %v", fields)
+	}
+	if fields["id"] == "" || fields["id"] == nil {
+		t.Fatal("expected generated id")
+	}
+
+	var evt Event
+	if err := codec.Unmarshal(raw, &evt); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if evt.EventType != "students.changed" || evt.Subject != "student/123" || !evt.Time.Equal(ts) {
+		t.Fatalf("unexpected event: %+v", evt)
+	}
+	if string(evt.Data) != `{"id":123}` {
+		t.Fatalf("unexpected data: %s", string(evt.Data))
+	}
+}
+
+func TestCloudEventsCodecRequiresSource(t *testing.T) {
+	if _, err := (CloudEventsCodec{}).Marshal(Event{EventType: "students.changed"}); err == nil {
+		t.Fatal("expected error for missing source")
+	}
+}
+
+func TestCloudEventsCodecBinaryData(t *testing.T) {
+	codec := CloudEventsCodec{Source: "/students-api"}
+
+	raw, err := codec.Marshal(Event{EventType: "students.changed", Data: []byte{0xff, 0x00}})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var evt Event
+	if err := codec.Unmarshal(raw, &evt); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if string(evt.Data) != "\xff\x00" {
+		t.Fatalf("unexpected data: %q", evt.Data)
+	}
+}
+
+func TestCloudEventsEncoderIngestsStructuredEvent(t *testing.T) {
+	encoder := applyEventNamePrefix(codecEventEncoder(CloudEventsCodec{}), "app")
+
+	raw := []byte(`{"specversion":"1.0","id":"A234-1234","source":"/billing","type":"payments.received","datacontenttype":"application/json","data":{"amount":10}}`)
+	eventType, data, err := encoder(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if eventType != "app.payments.received" {
+		t.Fatalf("unexpected event type: %s", eventType)
+	}
+	if string(data) != `{"amount":10}` {
+		t.Fatalf("unexpected data: %s", string(data))
+	}
+}
+
+func TestCloudEventsCodecRejectsUnsupportedVersion(t *testing.T) {
+	var evt Event
+	raw := []byte(`{"specversion":"0.3","id":"1","source":"/x","type":"t"}`)
+	if err := (CloudEventsCodec{}).Unmarshal(raw, &evt); err == nil {
+		t.Fatal("expected error for unsupported specversion")
+	}
+}
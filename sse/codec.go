@@ -0,0 +1,83 @@
+package sse
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+)
+
+// CodecHeader is the request header a non-browser client sets to receive
+// event envelopes encoded with one of Options.Codecs instead of plain JSON
+// data. Binary envelopes are base64-encoded in the SSE data line.
+const CodecHeader = "X-Event-Codec"
+
+type Codec interface {
+	Name() string
+	Marshal(evt Event) ([]byte, error)
+	Unmarshal(raw []byte, evt *Event) error
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string { return "json" }
+
+func (JSONCodec) Marshal(evt Event) ([]byte, error) {
+	return json.Marshal(evt)
+}
+
+func (JSONCodec) Unmarshal(raw []byte, evt *Event) error {
+	return json.Unmarshal(raw, evt)
+}
+
+func codecEventEncoder(codec Codec) EventEncoder {
+	return func(raw []byte) (string, []byte, error) {
+		var evt Event
+		if err := codec.Unmarshal(raw, &evt); err != nil || evt.EventType == "" {
+			return "message", raw, nil
+		}
+		return evt.EventType, browserData(evt.Data), nil
+	}
+}
+
+// transcodingEventEncoder decodes envelopes published with in and re-encodes
+// them with out for clients that negotiated a codec via CodecHeader.
+func transcodingEventEncoder(in, out Codec) EventEncoder {
+	return func(raw []byte) (string, []byte, error) {
+		var evt Event
+		if err := in.Unmarshal(raw, &evt); err != nil {
+			return "", nil, err
+		}
+		if evt.EventType == "" {
+			evt.EventType = "message"
+		}
+		encoded, err := out.Marshal(evt)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, ok := out.(JSONCodec); ok {
+			return evt.EventType, encoded, nil
+		}
+		return evt.EventType, []byte(base64.StdEncoding.EncodeToString(encoded)), nil
+	}
+}
+
+// browserData returns data unchanged when it is JSON and as a base64 JSON
+// string otherwise, so binary payloads survive the text-only SSE data line.
+func browserData(data []byte) []byte {
+	if len(data) == 0 {
+		return []byte(`{}`)
+	}
+	if json.Valid(data) {
+		return data
+	}
+	return []byte(strconv.Quote(base64.StdEncoding.EncodeToString(data)))
+}
+
+func findCodec(name string, codecs ...Codec) Codec {
+	for _, c := range codecs {
+		if c != nil && c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
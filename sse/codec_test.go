@@ -0,0 +1,101 @@
+package sse
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type upperCodec struct{}
+
+func (upperCodec) Name() string { return "upper" }
+
+func (upperCodec) Marshal(evt Event) ([]byte, error) {
+	return []byte(strings.ToUpper(evt.EventType) + "|" + string(evt.Data)), nil
+}
+
+func (upperCodec) Unmarshal(raw []byte, evt *Event) error {
+	typ, data, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return errors.New("invalid envelope")
+	}
+	evt.EventType = strings.ToLower(typ)
+	evt.Data = []byte(data)
+	return nil
+}
+
+func TestCodecEventEncoderBinaryData(t *testing.T) {
+	encoder := codecEventEncoder(upperCodec{})
+
+	eventType, data, err := encoder([]byte("STUDENTS.CHANGED|\x01\x02"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if eventType != "students.changed" {
+		t.Fatalf("unexpected event type: %s", eventType)
+	}
+	want := `"` + base64.StdEncoding.EncodeToString([]byte{1, 2}) + `"`
+	if string(data) != want {
+		t.Fatalf("unexpected data: %s", string(data))
+	}
+}
+
+func TestCodecEventEncoderJSONData(t *testing.T) {
+	encoder := codecEventEncoder(upperCodec{})
+
+	_, data, err := encoder([]byte(`STUDENTS.CHANGED|{"id":1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != `{"id":1}` {
+		t.Fatalf("unexpected data: %s", string(data))
+	}
+}
+
+func TestTranscodingEventEncoder(t *testing.T) {
+	encoder := transcodingEventEncoder(JSONCodec{}, upperCodec{})
+
+	eventType, data, err := encoder([]byte(`{"event_type":"students.changed","data":{"id":1}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if eventType != "students.changed" {
+		t.Fatalf("unexpected event type: %s", eventType)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		t.Fatalf("expected base64 data: %v", err)
+	}
+	if string(decoded) != `STUDENTS.CHANGED|{"id":1}` {
+		t.Fatalf("unexpected envelope: %s", string(decoded))
+	}
+}
+
+func TestPublisherUsesCodec(t *testing.T) {
+	broker := newTestBroker()
+	pub := NewPublisherWithOptions(broker, PublisherOptions{Codec: upperCodec{}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	if err := pub.PublishType(context.Background(), "scope:1:students", "students.changed"); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		if string(msg.Payload) != "STUDENTS.CHANGED|" {
+			t.Fatalf("unexpected payload: %s", string(msg.Payload))
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout waiting for event")
+	}
+}
@@ -0,0 +1,150 @@
+package sse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type CompressionAlgorithm int
+
+const (
+	CompressionNone CompressionAlgorithm = iota
+	CompressionGzip
+	CompressionZstd
+)
+
+type Compression struct {
+	Algorithm CompressionAlgorithm
+	// MinSize is the smallest payload worth compressing. Default 1024.
+	MinSize int
+}
+
+// compressedMarker prefixes compressed broker payloads and is followed by one
+// byte naming the algorithm. No codec output starts with a NUL byte.
+const compressedMarker = "\x00z"
+
+const (
+	markerGzip = 'g'
+	markerZstd = 'z'
+)
+
+// maxDecompressedSize bounds how far a compressed payload may expand.
+const maxDecompressedSize = 16 << 20
+
+var errPayloadTooLarge = fmt.Errorf("decompressed payload exceeds %d bytes", maxDecompressedSize)
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedSize))
+	})
+)
+
+// compressPayload compresses payload when it is at least c.MinSize bytes and
+// compression actually makes it smaller.
+func compressPayload(c Compression, payload []byte) ([]byte, error) {
+	minSize := c.MinSize
+	if minSize == 0 {
+		minSize = 1024
+	}
+	if c.Algorithm == CompressionNone || len(payload) < minSize {
+		return payload, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(compressedMarker)
+
+	switch c.Algorithm {
+	case CompressionGzip:
+		buf.WriteByte(markerGzip)
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZstd:
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(markerZstd)
+		buf.Write(enc.EncodeAll(payload, nil))
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm %d", c.Algorithm)
+	}
+
+	if buf.Len() >= len(payload) {
+		return payload, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressPayload reverses compressPayload. Payloads without the marker are
+// returned unchanged.
+func decompressPayload(payload []byte) ([]byte, error) {
+	if !bytes.HasPrefix(payload, []byte(compressedMarker)) || len(payload) <= len(compressedMarker) {
+		return payload, nil
+	}
+	algo, body := payload[len(compressedMarker)], payload[len(compressedMarker)+1:]
+
+	switch algo {
+	case markerGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		out, err := io.ReadAll(io.LimitReader(zr, maxDecompressedSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > maxDecompressedSize {
+			return nil, errPayloadTooLarge
+		}
+		return out, nil
+	case markerZstd:
+		dec, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		out, err := dec.DecodeAll(body, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+			return nil, errPayloadTooLarge
+		}
+		return out, err
+	default:
+		return nil, fmt.Errorf("unknown compression marker %q", algo)
+	}
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(part, ";")
+			name = strings.TrimSpace(name)
+			if name != "gzip" && name != "*" {
+				continue
+			}
+			q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !ok {
+				return true
+			}
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
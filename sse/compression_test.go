@@ -0,0 +1,134 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressPayloadRoundTrip(t *testing.T) {
+	payload := []byte(`{"event_type":"x","data":"` + strings.Repeat("abc", 1000) + `"}`)
+
+	for _, algo := range []CompressionAlgorithm{CompressionGzip, CompressionZstd} {
+		compressed, err := compressPayload(Compression{Algorithm: algo}, payload)
+		if err != nil {
+			t.Fatalf("compress failed: %v", err)
+		}
+		if len(compressed) >= len(payload) || !bytes.HasPrefix(compressed, []byte(compressedMarker)) {
+			t.Fatalf("expected marked, smaller payload for algorithm %d", algo)
+		}
+
+		got, err := decompressPayload(compressed)
+		if err != nil {
+			t.Fatalf("decompress failed: %v", err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Fatalf("round trip mismatch for algorithm %d", algo)
+		}
+	}
+}
+
+func TestCompressPayloadSkipsSmallPayloads(t *testing.T) {
+	payload := []byte(`{"event_type":"x"}`)
+	got, err := compressPayload(Compression{Algorithm: CompressionGzip}, payload)
+	if err != nil {
+		t.Fatalf("compress failed: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("expected payload below MinSize to be unchanged, got %q", got)
+	}
+
+	if got, err := decompressPayload(payload); err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("expected unmarked payload to pass through, got %q, %v", got, err)
+	}
+}
+
+func TestDecompressPayloadRejectsUnknownMarker(t *testing.T) {
+	if _, err := decompressPayload([]byte(compressedMarker + "?data")); err == nil {
+		t.Fatal("expected error for unknown marker")
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	cases := map[string]bool{
+		"":                    false,
+		"gzip":                true,
+		"deflate, gzip;q=1.0": true,
+		"br, gzip;q=0":        false,
+		"*":                   true,
+		"identity":            false,
+	}
+	for header, want := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set("Accept-Encoding", header)
+		}
+		if got := acceptsGzip(r); got != want {
+			t.Fatalf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestSSEHandlerGzipStreamWithCompressedPayload(t *testing.T) {
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router:      func(*Principal) []string { return []string{"scope:1:*"} },
+		GzipStream:  true,
+		Compression: Compression{Algorithm: CompressionZstd, MinSize: 1},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("unexpected content encoding: %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to open gzip stream: %v", err)
+	}
+	reader := bufio.NewReader(zr)
+	for i := 0; i < 2; i++ {
+		if _, err := readLineWithTimeout(reader, time.Second); err != nil {
+			t.Fatalf("failed to read retry line: %v", err)
+		}
+	}
+
+	data := `"` + strings.Repeat("x", 2048) + `"`
+	if err := server.Publisher().PublishEvent(context.Background(), "scope:1:students", Event{
+		EventType: "students.changed",
+		Data:      []byte(data),
+	}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	line, err := readLineWithTimeout(reader, time.Second)
+	if err != nil || line != "event: students.changed" {
+		t.Fatalf("unexpected event line: %q, %v", line, err)
+	}
+	line, err = readLineWithTimeout(reader, time.Second)
+	if err != nil || line != "data: "+data {
+		t.Fatalf("unexpected data line of %d bytes, %v", len(line), err)
+	}
+}
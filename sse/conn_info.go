@@ -0,0 +1,49 @@
+package sse
+
+import (
+	"context"
+	"time"
+)
+
+// ConnInfo describes one SSE connection.
+type ConnInfo struct {
+	ID          string
+	Principal   *Principal
+	RemoteAddr  string
+	UserAgent   string
+	ConnectedAt time.Time
+	Patterns    []string
+	// Context is the stream context, carrying values attached by
+	// StreamMiddleware.
+	Context context.Context
+}
+
+type DisconnectReason string
+
+const (
+	DisconnectClientClosed    DisconnectReason = "client closed"
+	DisconnectWriteFailed     DisconnectReason = "write failed"
+	DisconnectBackpressure    DisconnectReason = "backpressure"
+	DisconnectSlowConsumer    DisconnectReason = "slow consumer"
+	DisconnectServerShutdown  DisconnectReason = "server shutdown"
+	DisconnectAuthExpired     DisconnectReason = "auth expired"
+	DisconnectLifetimeReached DisconnectReason = "lifetime reached"
+)
+
+func (h Hooks) clientDropped(scopeID int64, info ConnInfo, reason string) {
+	if h.OnClientDropped != nil {
+		h.OnClientDropped(scopeID, reason)
+	}
+	if h.OnConnDropped != nil {
+		h.OnConnDropped(info, reason)
+	}
+}
+
+func (h Hooks) slowConsumer(scopeID int64, info ConnInfo, stats SlowConsumerStats) {
+	if h.OnSlowConsumer != nil {
+		h.OnSlowConsumer(scopeID, stats)
+	}
+	if h.OnConnSlow != nil {
+		h.OnConnSlow(info, stats)
+	}
+}
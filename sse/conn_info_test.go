@@ -0,0 +1,226 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSSEHandlerConnInfoHooks(t *testing.T) {
+	connected := make(chan ConnInfo, 1)
+	disconnected := make(chan DisconnectReason, 1)
+
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 42, ScopeID: 1}, nil
+		}),
+		Router:                func(*Principal) []string { return []string{"scope:1:*"} },
+		MaxConnectionLifetime: 50 * time.Millisecond,
+		Hooks: Hooks{
+			OnConnect: func(info ConnInfo) { connected <- info },
+			OnDisconnect: func(info ConnInfo, reason DisconnectReason) {
+				disconnected <- reason
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("User-Agent", "conn-info-test")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	select {
+	case info := <-connected:
+		if info.ID == "" || info.Principal.UserID != 42 || info.UserAgent != "conn-info-test" ||
+			info.RemoteAddr == "" || info.ConnectedAt.IsZero() || len(info.Patterns) != 1 {
+			t.Fatalf("unexpected conn info: %+v", info)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for OnConnect")
+	}
+
+	select {
+	case reason := <-disconnected:
+		if reason != DisconnectLifetimeReached {
+			t.Fatalf("unexpected disconnect reason: %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for OnDisconnect")
+	}
+}
+
+func TestSSEHandlerRejectsExpiredPrincipal(t *testing.T) {
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 1, ScopeID: 1, ExpiresAt: time.Now().Add(-time.Minute)}, nil
+		}),
+		Router: func(*Principal) []string { return []string{"scope:1:*"} },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
+
+func TestSSEHandlerReportsAuthExpiry(t *testing.T) {
+	disconnected := make(chan DisconnectReason, 1)
+
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 1, ScopeID: 1, ExpiresAt: time.Now().Add(50 * time.Millisecond)}, nil
+		}),
+		Router:                func(*Principal) []string { return []string{"scope:1:*"} },
+		MaxConnectionLifetime: time.Hour,
+		Hooks: Hooks{
+			OnDisconnect: func(_ ConnInfo, reason DisconnectReason) { disconnected <- reason },
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	select {
+	case reason := <-disconnected:
+		if reason != DisconnectAuthExpired {
+			t.Fatalf("unexpected disconnect reason: %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for OnDisconnect")
+	}
+}
+
+func TestSSEHandlerReportsBackpressureDisconnect(t *testing.T) {
+	disconnected := make(chan DisconnectReason, 1)
+	connected := make(chan struct{})
+
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router:           func(*Principal) []string { return []string{"scope:1:*"} },
+		Backpressure:     BackpressureDisconnect,
+		ClientBufferSize: 1,
+		Hooks: Hooks{
+			OnConnect:    func(ConnInfo) { close(connected) },
+			OnDisconnect: func(_ ConnInfo, reason DisconnectReason) { disconnected <- reason },
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hub := server.hubs.getOrCreateHub(1, []string{"scope:1:*"})
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err := readLineWithTimeout(bufio.NewReader(resp.Body), time.Second); err != nil {
+		t.Fatalf("failed to read retry line: %v", err)
+	}
+	<-connected
+
+	// Broadcasting directly fills the one-slot buffer before the handler
+	// can drain it.
+	for i := 0; i < 2; i++ {
+		hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: []byte(`{"event_type":"x"}`)})
+	}
+
+	select {
+	case reason := <-disconnected:
+		if reason != DisconnectBackpressure {
+			t.Fatalf("unexpected disconnect reason: %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for OnDisconnect")
+	}
+}
+
+func TestHubStopClosesClientsWithShutdownReason(t *testing.T) {
+	opts := Options{}
+	applyDefaultOptions(&opts)
+
+	hub := newHub(context.Background(), newTestBroker(), opts, 1, []string{"scope:1:*"})
+	c := hub.addClient(1, ConnInfo{ID: "c1"})
+	hub.stop()
+
+	if c.closeReason() != DisconnectServerShutdown {
+		t.Fatalf("unexpected close reason: %q", c.closeReason())
+	}
+}
+
+func TestConnectionEnd(t *testing.T) {
+	now := time.Now()
+	soon, later := now.Add(time.Minute), now.Add(time.Hour)
+
+	cases := []struct {
+		expiresAt time.Time
+		lifetime  time.Duration
+		want      time.Time
+	}{
+		{time.Time{}, 0, time.Time{}},
+		{time.Time{}, time.Hour, later},
+		{soon, 0, soon},
+		{soon, time.Hour, soon},
+		{later, time.Minute, soon},
+	}
+	for _, tc := range cases {
+		got := connectionEnd(now, &Principal{ExpiresAt: tc.expiresAt}, tc.lifetime)
+		if !got.Equal(tc.want) {
+			t.Fatalf("connectionEnd(%v, %v) = %v, want %v", tc.expiresAt, tc.lifetime, got, tc.want)
+		}
+	}
+}
+
+func TestHubReportsDroppedEventsWithConnInfo(t *testing.T) {
+	var scopes []int64
+	var conns []string
+	opts := Options{
+		Hooks: Hooks{
+			OnClientDropped: func(scopeID int64, _ string) { scopes = append(scopes, scopeID) },
+			OnConnDropped:   func(info ConnInfo, _ string) { conns = append(conns, info.ID) },
+		},
+	}
+	applyDefaultOptions(&opts)
+
+	hub := newHub(context.Background(), newTestBroker(), opts, 1, []string{"scope:1:*"})
+	hub.addClient(1, ConnInfo{ID: "c1"})
+	defer hub.stop()
+
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: []byte("a")})
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: []byte("b")})
+
+	if len(scopes) != 1 || scopes[0] != 1 || len(conns) != 1 || conns[0] != "c1" {
+		t.Fatalf("unexpected drop hooks: scopes %v, conns %v", scopes, conns)
+	}
+}
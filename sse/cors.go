@@ -0,0 +1,130 @@
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CORSOptions struct {
+	// AllowedOrigins enables CORS and origin enforcement. Entries are exact
+	// origins ("https://app.example.com"), wildcard subdomains
+	// ("https://*.example.com", which does not match the apex) or "*",
+	// which cannot be combined with AllowCredentials. Requests carrying any
+	// other Origin are rejected with 403 before the principal is resolved;
+	// requests without an Origin header are allowed.
+	AllowedOrigins []string
+	// AllowCredentials lets EventSource send cookies (withCredentials).
+	AllowCredentials bool
+	// AllowedHeaders are accepted in preflight requests. Default
+	// Last-Event-ID and CodecHeader.
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+func (c CORSOptions) enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+func (c CORSOptions) validate() error {
+	if c.AllowCredentials {
+		for _, origin := range c.AllowedOrigins {
+			if origin == "*" {
+				return errors.New(`allowed origin "*" cannot be combined with AllowCredentials`)
+			}
+		}
+	}
+	return validateOrigins(c.AllowedOrigins)
+}
+
+func validateOrigins(origins []string) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || scheme == "" || host == "" || strings.Contains(host, "/") {
+			return fmt.Errorf("invalid allowed origin %q", origin)
+		}
+		if strings.Contains(host, "*") && (!strings.HasPrefix(host, "*.") || strings.Count(host, "*") > 1 || len(host) < 3) {
+			return fmt.Errorf("invalid allowed origin %q: only a leading *. wildcard is supported", origin)
+		}
+	}
+	return nil
+}
+
+func (c CORSOptions) allows(origin string) bool {
+	return matchOrigin(c.AllowedOrigins, origin)
+}
+
+func matchOrigin(origins []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range origins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(allowed, "*")
+		if !ok || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+		if sub := origin[len(prefix) : len(origin)-len(suffix)]; len(origin) > len(prefix)+len(suffix) && isHostLabels(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHostLabels(s string) bool {
+	for _, label := range strings.Split(s, ".") {
+		if label == "" {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// handleCORS writes CORS headers and reports whether the request is done:
+// either a rejected origin or an answered preflight.
+func handleCORS(w http.ResponseWriter, r *http.Request, c CORSOptions) (done bool, status int) {
+	if !c.enabled() {
+		return false, 0
+	}
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false, 0
+	}
+	if !c.allows(origin) {
+		return true, http.StatusForbidden
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		w.Header().Set("Access-Control-Expose-Headers", CodecHeader)
+		return false, 0
+	}
+
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	w.Header().Set("Access-Control-Allow-Methods", http.MethodGet)
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+	if c.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+	}
+	return true, http.StatusNoContent
+}
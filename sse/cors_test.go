@@ -0,0 +1,183 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORSAllows(t *testing.T) {
+	c := CORSOptions{AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"}}
+	cases := map[string]bool{
+		"https://app.example.com":       true,
+		"https://APP.example.com":       true,
+		"http://app.example.com":        false,
+		"https://a.example.org":         true,
+		"https://a.b.example.org":       true,
+		"https://example.org":           false,
+		"https://.example.org":          false,
+		"https://evil.com/.example.org": false,
+		"https://evilexample.org":       false,
+		"https://app.example.com.evil":  false,
+	}
+	for origin, want := range cases {
+		if got := c.allows(origin); got != want {
+			t.Fatalf("allows(%q) = %v, want %v", origin, got, want)
+		}
+	}
+
+	if !(CORSOptions{AllowedOrigins: []string{"*"}}).allows("https://any.test") {
+		t.Fatal("expected * to allow any origin")
+	}
+}
+
+func TestNewServerValidatesCORS(t *testing.T) {
+	for _, origin := range []string{"app.example.com", "https://*", "https://a.*.com", "https://*.*.com", "https://x.com/path"} {
+		_, err := NewServer(newTestBroker(), Options{
+			Resolver: resolverFunc(func(*http.Request) (*Principal, error) { return &Principal{}, nil }),
+			Router:   func(*Principal) []string { return nil },
+			CORS:     CORSOptions{AllowedOrigins: []string{origin}},
+		})
+		if err == nil {
+			t.Fatalf("expected error for origin %q", origin)
+		}
+	}
+
+	_, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) { return &Principal{}, nil }),
+		Router:   func(*Principal) []string { return nil },
+		CORS:     CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+	})
+	if err == nil {
+		t.Fatal("expected error for * with AllowCredentials")
+	}
+}
+
+func TestSSEHandlerEnforcesAllowedOriginsWithoutCORS(t *testing.T) {
+	var resolved bool
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			resolved = true
+			return &Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router:         func(*Principal) []string { return []string{"scope:1:*"} },
+		AllowedOrigins: []string{"https://app.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.test")
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("unexpected CORS header: %v", rec.Header())
+	}
+	if resolved {
+		t.Fatal("expected principal not to be resolved")
+	}
+
+	if _, err := NewServer(newTestBroker(), Options{
+		Resolver:       resolverFunc(func(*http.Request) (*Principal, error) { return &Principal{}, nil }),
+		Router:         func(*Principal) []string { return nil },
+		AllowedOrigins: []string{"app.example.com"},
+	}); err == nil {
+		t.Fatal("expected error for invalid allowed origin")
+	}
+}
+
+func newCORSTestServer(t *testing.T, resolved *bool) *Server {
+	t.Helper()
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			*resolved = true
+			return &Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router: func(*Principal) []string { return []string{"scope:1:*"} },
+		CORS: CORSOptions{
+			AllowedOrigins:   []string{"https://*.example.com"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return server
+}
+
+func TestSSEHandlerRejectsDisallowedOrigin(t *testing.T) {
+	var resolved bool
+	server := newCORSTestServer(t, &resolved)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.test")
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("unexpected CORS header: %v", rec.Header())
+	}
+	if resolved {
+		t.Fatal("expected principal not to be resolved")
+	}
+}
+
+func TestSSEHandlerAnswersPreflight(t *testing.T) {
+	var resolved bool
+	server := newCORSTestServer(t, &resolved)
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "last-event-id")
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent || resolved {
+		t.Fatalf("unexpected preflight response: %d, resolved=%v", rec.Code, resolved)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET",
+		"Access-Control-Allow-Headers":     "Last-Event-ID, X-Event-Codec",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Fatalf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestSSEHandlerSetsCORSHeadersOnStream(t *testing.T) {
+	var resolved bool
+	server := newCORSTestServer(t, &resolved)
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK ||
+		resp.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		resp.Header.Get("Access-Control-Allow-Credentials") != "true" ||
+		resp.Header.Get("Vary") != "Origin" {
+		t.Fatalf("unexpected response: %d %v", resp.StatusCode, resp.Header)
+	}
+}
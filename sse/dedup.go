@@ -0,0 +1,53 @@
+package sse
+
+import (
+	"context"
+	"sync"
+)
+
+// Deduplicator suppresses repeated publishes of the same event across
+// publishers. Claim reports whether key was claimed by this call; Release
+// frees a claim whose publish failed so a retry can go through.
+type Deduplicator interface {
+	Claim(ctx context.Context, key string) (bool, error)
+	Release(ctx context.Context, key string) error
+}
+
+func dedupKey(channel, id string) string {
+	return channel + "\x00" + id
+}
+
+// dedupWindow remembers the last size keys it has seen.
+type dedupWindow struct {
+	mu   sync.Mutex
+	keys []string
+	next int
+	seen map[string]struct{}
+}
+
+func newDedupWindow(size int) *dedupWindow {
+	if size <= 0 {
+		return nil
+	}
+	return &dedupWindow{
+		keys: make([]string, size),
+		seen: make(map[string]struct{}, size),
+	}
+}
+
+// seenBefore reports whether key is in the window, adding it if not.
+func (w *dedupWindow) seenBefore(key string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.seen[key]; ok {
+		return true
+	}
+	if old := w.keys[w.next]; old != "" {
+		delete(w.seen, old)
+	}
+	w.keys[w.next] = key
+	w.next = (w.next + 1) % len(w.keys)
+	w.seen[key] = struct{}{}
+	return false
+}
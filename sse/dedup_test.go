@@ -0,0 +1,74 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestDedupWindowEvictsOldest(t *testing.T) {
+	w := newDedupWindow(2)
+
+	if w.seenBefore("a") || w.seenBefore("b") {
+		t.Fatal("expected new keys to be unseen")
+	}
+	if !w.seenBefore("a") {
+		t.Fatal("expected repeated key to be seen")
+	}
+	w.seenBefore("c")
+	if w.seenBefore("a") {
+		t.Fatal("expected oldest key to be evicted")
+	}
+}
+
+func TestHubDropsDuplicateEvents(t *testing.T) {
+	duplicates := 0
+	opts := Options{Hooks: Hooks{OnDuplicateEvent: func(int64) { duplicates++ }}}
+	applyDefaultOptions(&opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := newHub(ctx, newTestBroker(), opts, 1, []string{"scope:1:*"})
+	c := hub.addClient(4, ConnInfo{})
+	defer hub.stop()
+
+	payload, _ := json.Marshal(Event{ID: "evt-1", EventType: "students.changed"})
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: payload})
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: payload})
+	hub.broadcast(BrokerMsg{Channel: "scope:1:plans", Payload: payload})
+
+	if duplicates != 1 {
+		t.Fatalf("unexpected duplicate count: %d", duplicates)
+	}
+	for _, want := range []int{1, 2} {
+		if _, _, ok := c.next(); !ok {
+			t.Fatalf("expected message %d", want)
+		}
+	}
+	if _, _, ok := c.next(); ok {
+		t.Fatal("expected duplicate to be dropped")
+	}
+}
+
+func TestPublisherAssignsEventID(t *testing.T) {
+	broker := newTestBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	if err := NewPublisher(broker).PublishType(context.Background(), "scope:1:students", "students.changed"); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	var evt Event
+	if err := json.Unmarshal((<-sub.Channel()).Payload, &evt); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if evt.ID == "" {
+		t.Fatal("expected generated event id")
+	}
+}
@@ -1,36 +1,14 @@
 package sse
 
-import (
-	"encoding/json"
-	"strings"
-)
+import "strings"
 
-func defaultEventEncoder(raw []byte) (eventtype string, data []byte, err error) {
-	evtType := "message"
-	data = raw
-
-	var evt Event
-	if err := json.Unmarshal(raw, &evt); err != nil {
-		return evtType, data, nil
-	}
-	if evt.EventType != "" {
-		evtType = evt.EventType
-		if len(evt.Data) == 0 {
-			data = []byte(`{}`)
-		} else {
-			data = []byte(evt.Data)
-		}
-	}
-	return evtType, data, nil
-}
+var defaultEventEncoder = codecEventEncoder(JSONCodec{})
 
 func applyEventNamePrefix(encoder EventEncoder, prefix string) EventEncoder {
 	if encoder == nil {
 		return nil
 	}
-	prefix = strings.TrimSpace(prefix)
-	prefix = strings.TrimSuffix(prefix, ".")
-	if prefix == "" {
+	if normalizeEventNamePrefix(prefix) == "" {
 		return encoder
 	}
 
@@ -39,9 +17,19 @@ func applyEventNamePrefix(encoder EventEncoder, prefix string) EventEncoder {
 		if err != nil || eventType == "" {
 			return eventType, data, err
 		}
-		if strings.HasPrefix(eventType, prefix+".") {
-			return eventType, data, err
-		}
-		return prefix + "." + eventType, data, err
+		return prefixEventName(prefix, eventType), data, err
+	}
+}
+
+func prefixEventName(prefix, eventType string) string {
+	prefix = normalizeEventNamePrefix(prefix)
+	if prefix == "" || strings.HasPrefix(eventType, prefix+".") {
+		return eventType
 	}
+	return prefix + "." + eventType
+}
+
+func normalizeEventNamePrefix(prefix string) string {
+	prefix = strings.TrimSpace(prefix)
+	return strings.TrimSuffix(prefix, ".")
 }
@@ -0,0 +1,151 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type EventRegistry struct {
+	mu     sync.RWMutex
+	byType map[reflect.Type]registeredEvent
+	byName map[string]reflect.Type
+}
+
+type registeredEvent struct {
+	name    string
+	pattern string
+	schema  map[string]any
+	channel any
+}
+
+type EventCatalogEntry struct {
+	Name    string         `json:"name"`
+	Channel string         `json:"channel"`
+	Schema  map[string]any `json:"schema"`
+}
+
+func NewEventRegistry() *EventRegistry {
+	return &EventRegistry{
+		byType: make(map[reflect.Type]registeredEvent),
+		byName: make(map[string]reflect.Type),
+	}
+}
+
+// RegisterEvent binds T to an event type name and a function deriving the
+// channel from a value. The catalog pattern is derived by calling channel
+// with the zero T; use RegisterEventWithPattern when that panics or when the
+// scope or topic depend on the value. Each type and each name can be
+// registered once.
+func RegisterEvent[T any](r *EventRegistry, name string, channel func(T) ChannelName) error {
+	if channel == nil {
+		return fmt.Errorf("channel function for %s cannot be nil", name)
+	}
+	pattern, err := zeroChannelPattern(channel)
+	if err != nil {
+		return fmt.Errorf("cannot derive channel pattern for %s, use RegisterEventWithPattern: %w", name, err)
+	}
+	return RegisterEventWithPattern(r, name, pattern, channel)
+}
+
+// RegisterEventWithPattern is RegisterEvent with the catalog pattern given
+// explicitly, e.g. "gym:{scope_id}:students".
+func RegisterEventWithPattern[T any](r *EventRegistry, name, pattern string, channel func(T) ChannelName) error {
+	if name == "" {
+		return fmt.Errorf("event type cannot be empty")
+	}
+	if channel == nil {
+		return fmt.Errorf("channel function for %s cannot be nil", name)
+	}
+	if _, err := ParseChannel(strings.Replace(pattern, "{scope_id}", "0", 1)); err != nil {
+		return fmt.Errorf("invalid channel pattern for %s: %w", name, err)
+	}
+
+	typ := reflect.TypeFor[T]()
+	def := registeredEvent{
+		name:    name,
+		pattern: pattern,
+		schema:  jsonSchema(typ),
+		channel: channel,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byType[typ]; exists {
+		return fmt.Errorf("event for type %s already registered", typ)
+	}
+	if _, exists := r.byName[name]; exists {
+		return fmt.Errorf("event %s already registered", name)
+	}
+	r.byType[typ] = def
+	r.byName[name] = typ
+	return nil
+}
+
+func zeroChannelPattern[T any](channel func(T) ChannelName) (pattern string, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("channel function panicked on zero value: %v", v)
+		}
+	}()
+
+	var zero T
+	c := channel(zero)
+	if _, err := ParseChannel(c.String()); err != nil {
+		return "", err
+	}
+	return c.Scope + ":{scope_id}:" + c.Topic, nil
+}
+
+func (r *EventRegistry) Catalog() []EventCatalogEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]EventCatalogEntry, 0, len(r.byType))
+	for _, def := range r.byType {
+		entries = append(entries, EventCatalogEntry{Name: def.name, Channel: def.pattern, Schema: def.schema})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+func lookupEvent[T any](r *EventRegistry) (registeredEvent, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.byType[reflect.TypeFor[T]()]
+	return def, ok
+}
+
+type TypedPublisher[T any] struct {
+	publisher *Publisher
+	name      string
+	channel   func(T) ChannelName
+}
+
+func NewTypedPublisher[T any](publisher *Publisher, registry *EventRegistry) (*TypedPublisher[T], error) {
+	def, ok := lookupEvent[T](registry)
+	if !ok {
+		return nil, fmt.Errorf("event for type %s is not registered", reflect.TypeFor[T]())
+	}
+	return &TypedPublisher[T]{
+		publisher: publisher,
+		name:      def.name,
+		channel:   def.channel.(func(T) ChannelName),
+	}, nil
+}
+
+func (p *TypedPublisher[T]) Publish(ctx context.Context, v T) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return p.publisher.PublishEvent(ctx, p.channel(v).String(), Event{
+		EventType: p.name,
+		Data:      data,
+	})
+}
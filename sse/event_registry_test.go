@@ -0,0 +1,124 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type studentChanged struct {
+	ID    int64  `json:"id"`
+	GymID int64  `json:"gym_id"`
+	Note  string `json:"note,omitempty"`
+}
+
+func studentChannel(e studentChanged) ChannelName {
+	return Channel("gym", e.GymID, "students")
+}
+
+func TestTypedPublisherDerivesTypeAndChannel(t *testing.T) {
+	registry := NewEventRegistry()
+	if err := RegisterEvent(registry, "students.changed", studentChannel); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	broker := newTestBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "gym:3:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	pub, err := NewTypedPublisher[studentChanged](NewPublisher(broker), registry)
+	if err != nil {
+		t.Fatalf("typed publisher failed: %v", err)
+	}
+	if err := pub.Publish(context.Background(), studentChanged{ID: 1, GymID: 3}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		if msg.Channel != "gym:3:students" {
+			t.Fatalf("unexpected channel: %s", msg.Channel)
+		}
+		var evt Event
+		if err := json.Unmarshal(msg.Payload, &evt); err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		if evt.EventType != "students.changed" || string(evt.Data) != `{"id":1,"gym_id":3}` {
+			t.Fatalf("unexpected event: %+v", evt)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout waiting for event")
+	}
+}
+
+func TestRegisterEventRejectsDuplicates(t *testing.T) {
+	registry := NewEventRegistry()
+	if err := RegisterEvent(registry, "students.changed", studentChannel); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if err := RegisterEvent(registry, "students.updated", studentChannel); err == nil {
+		t.Fatal("expected error for duplicate type")
+	}
+	if err := RegisterEvent(registry, "students.changed", func(struct{ ID int }) ChannelName {
+		return Channel("gym", 0, "students")
+	}); err == nil {
+		t.Fatal("expected error for duplicate name")
+	}
+}
+
+func TestNewTypedPublisherUnregistered(t *testing.T) {
+	if _, err := NewTypedPublisher[studentChanged](NewPublisher(newTestBroker()), NewEventRegistry()); err == nil {
+		t.Fatal("expected error for unregistered type")
+	}
+}
+
+func TestEventRegistryCatalog(t *testing.T) {
+	registry := NewEventRegistry()
+	if err := RegisterEvent(registry, "students.changed", studentChannel); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	catalog := registry.Catalog()
+	if len(catalog) != 1 {
+		t.Fatalf("unexpected catalog: %+v", catalog)
+	}
+	entry := catalog[0]
+	if entry.Name != "students.changed" || entry.Channel != "gym:{scope_id}:students" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	props := entry.Schema["properties"].(map[string]any)
+	if !reflect.DeepEqual(props["id"], map[string]any{"type": "integer"}) {
+		t.Fatalf("unexpected id schema: %v", props["id"])
+	}
+	if !reflect.DeepEqual(entry.Schema["required"], []string{"id", "gym_id"}) {
+		t.Fatalf("unexpected required fields: %v", entry.Schema["required"])
+	}
+	if _, err := json.Marshal(catalog); err != nil {
+		t.Fatalf("catalog not serializable: %v", err)
+	}
+}
+
+func TestRegisterEventPointerType(t *testing.T) {
+	registry := NewEventRegistry()
+	channel := func(e *studentChanged) ChannelName { return Channel("gym", e.GymID, "students") }
+
+	if err := RegisterEvent(registry, "students.changed", channel); err == nil {
+		t.Fatal("expected error when the pattern cannot be derived")
+	}
+	if err := RegisterEventWithPattern(registry, "students.changed", "gym::students", channel); err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+	if err := RegisterEventWithPattern(registry, "students.changed", "gym:{scope_id}:students", channel); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if catalog := registry.Catalog(); len(catalog) != 1 || catalog[0].Channel != "gym:{scope_id}:students" {
+		t.Fatalf("unexpected catalog: %+v", catalog)
+	}
+}
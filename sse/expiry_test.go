@@ -0,0 +1,103 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPublisherConvertsTTLToExpiresAt(t *testing.T) {
+	broker := newTestBroker()
+	pub := NewPublisher(broker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	before := time.Now()
+	if err := pub.PublishEvent(context.Background(), "scope:1:students", Event{
+		EventType: "students.changed",
+		TTL:       time.Minute,
+	}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	msg := <-sub.Channel()
+	var evt Event
+	if err := json.Unmarshal(msg.Payload, &evt); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if evt.ExpiresAt.Before(before.Add(time.Minute)) || evt.ExpiresAt.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("unexpected expiry: %v", evt.ExpiresAt)
+	}
+}
+
+type recordingScheduler struct {
+	payload []byte
+}
+
+func (s *recordingScheduler) Schedule(_ context.Context, _ time.Time, _ string, payload []byte) (string, error) {
+	s.payload = payload
+	return "1", nil
+}
+
+func (s *recordingScheduler) Cancel(context.Context, string) error { return nil }
+
+func TestPublishAtCountsTTLFromDispatchTime(t *testing.T) {
+	scheduler := &recordingScheduler{}
+	pub := NewPublisherWithOptions(newTestBroker(), PublisherOptions{Scheduler: scheduler})
+
+	at := time.Now().Add(time.Hour)
+	if _, err := pub.PublishAt(context.Background(), at, "scope:1:students", Event{
+		EventType: "students.changed",
+		TTL:       time.Minute,
+	}); err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+
+	var evt Event
+	if err := json.Unmarshal(scheduler.payload, &evt); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if !evt.ExpiresAt.Equal(at.Add(time.Minute)) {
+		t.Fatalf("unexpected expiry: %v, want %v", evt.ExpiresAt, at.Add(time.Minute))
+	}
+}
+
+func TestHubDiscardsExpiredEvents(t *testing.T) {
+	expired := 0
+	opts := Options{
+		Hooks: Hooks{OnEventExpired: func(int64) { expired++ }},
+	}
+	applyDefaultOptions(&opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := newHub(ctx, newTestBroker(), opts, 1, []string{"scope:1:*"})
+	c := hub.addClient(4, ConnInfo{})
+	defer hub.stop()
+
+	stale, _ := json.Marshal(Event{EventType: "students.changed", ExpiresAt: time.Now().Add(-time.Second)})
+	fresh, _ := json.Marshal(Event{EventType: "students.changed", ExpiresAt: time.Now().Add(time.Minute)})
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: stale})
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: fresh})
+
+	if expired != 1 {
+		t.Fatalf("unexpected expired count: %d", expired)
+	}
+	msg, _, ok := c.next()
+	if !ok || string(msg.payload) != string(fresh) {
+		t.Fatalf("unexpected message: %q", msg.payload)
+	}
+	if msg.expiresAt.IsZero() {
+		t.Fatal("expected queued message to carry its expiry")
+	}
+	if _, _, ok := c.next(); ok {
+		t.Fatal("expected expired event to be discarded")
+	}
+}
@@ -2,17 +2,74 @@ package sse
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
-func newHandler(hubs *hubManager, opts Options) http.Handler {
+func newHandler(hubs *hubManager, opts Options, hopts HandlerOptions) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connID := newEventID()
+
+		rejectOrigin := func() {
+			requestLogger(r, opts, opts.Logger.With("conn_id", connID)).
+				WarnContext(r.Context(), "origin not allowed", "origin", r.Header.Get("Origin"))
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && len(opts.AllowedOrigins) > 0 && !matchOrigin(opts.AllowedOrigins, origin) {
+			rejectOrigin()
+			return
+		}
+		if done, status := handleCORS(w, r, opts.CORS); done {
+			if status != http.StatusForbidden {
+				w.WriteHeader(status)
+				return
+			}
+			rejectOrigin()
+			return
+		}
+
+		r, status, err := applyStreamMiddleware(w, r, hopts.Middleware)
+		ctx := r.Context()
+		if err != nil {
+			requestLogger(r, opts, opts.Logger.With("conn_id", connID)).
+				WarnContext(ctx, "stream rejected", "status", status, "error", err)
+			http.Error(w, err.Error(), status)
+			return
+		}
+
 		principal, err := opts.Resolver.Resolve(r)
 		if err != nil {
+			requestLogger(r, opts, opts.Logger.With("conn_id", connID)).
+				WarnContext(ctx, "failed to resolve principal", "error", err)
 			http.Error(w, fmt.Sprintf("failed to resolve principal: %v", err), http.StatusUnauthorized)
 			return
 		}
+		log := requestLogger(r, opts, opts.Logger.With(
+			"scope_id", principal.ScopeID, "user_id", principal.UserID, "conn_id", connID))
+		if !principal.ExpiresAt.IsZero() && !principal.ExpiresAt.After(time.Now()) {
+			log.WarnContext(ctx, "principal expired", "expires_at", principal.ExpiresAt)
+			http.Error(w, "credentials expired", http.StatusUnauthorized)
+			return
+		}
+
+		encoder := opts.EventEncoder
+		if hopts.EventEncoder != nil {
+			encoder = applyEventNamePrefix(func(raw []byte) (string, []byte, error) {
+				return hopts.EventEncoder(ctx, raw)
+			}, opts.EventNamePrefix)
+		}
+		if name := r.Header.Get(CodecHeader); name != "" {
+			codec := findCodec(name, append([]Codec{opts.Codec}, opts.Codecs...)...)
+			if codec == nil {
+				log.WarnContext(ctx, "unsupported codec", "codec", name)
+				http.Error(w, fmt.Sprintf("unsupported codec: %s", name), http.StatusNotAcceptable)
+				return
+			}
+			encoder = applyEventNamePrefix(transcodingEventEncoder(opts.Codec, codec), opts.EventNamePrefix)
+			w.Header().Set(CodecHeader, codec.Name())
+		}
 
 		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
 		w.Header().Set("Cache-Control", "no-cache, no-transform")
@@ -22,61 +79,202 @@ func newHandler(hubs *hubManager, opts Options) http.Handler {
 		for k, v := range opts.Headers {
 			w.Header().Set(k, v)
 		}
+		if hopts.Headers != nil {
+			for k, v := range hopts.Headers(r, principal) {
+				w.Header()[http.CanonicalHeaderKey(k)] = v
+			}
+		}
 
-		flusher, ok := w.(http.Flusher)
-		if !ok {
+		if _, ok := w.(http.Flusher); !ok {
+			log.ErrorContext(ctx, "streaming unsupported by response writer")
 			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
 			return
 		}
 
-		hub := hubs.getOrCreateHub(principal.ScopeID, opts.Router(principal))
-		client := hub.addClient(opts.ClientBufferSize)
+		patterns := opts.Router(principal)
+		if err := checkChannels(opts.ChannelValidation, validateRoutes(patterns, principal.ScopeID), func(err error) {
+			log.WarnContext(ctx, "invalid channel routes", "patterns", patterns, "error", err)
+			if opts.Hooks.OnError != nil {
+				opts.Hooks.OnError(ctx, err)
+			}
+		}); err != nil {
+			log.ErrorContext(ctx, "invalid channel routes", "patterns", patterns, "error", err)
+			if opts.Hooks.OnError != nil {
+				opts.Hooks.OnError(ctx, err)
+			}
+			http.Error(w, "invalid channel routes", http.StatusInternalServerError)
+			return
+		}
+
+		info := ConnInfo{
+			ID:          connID,
+			Principal:   principal,
+			RemoteAddr:  r.RemoteAddr,
+			UserAgent:   r.UserAgent(),
+			ConnectedAt: time.Now(),
+			Patterns:    patterns,
+			Context:     ctx,
+		}
+
+		hub := hubs.getOrCreateHub(principal.ScopeID, patterns)
+		client := hub.addClient(opts.ClientBufferSize, info)
 		defer hub.removeClient(client)
 
+		log.Log(ctx, opts.LogLevels.Connect.Level(), "client connected", "patterns", patterns)
 		if opts.Hooks.OnClientConnect != nil {
 			opts.Hooks.OnClientConnect(principal.ScopeID)
 		}
+		if opts.Hooks.OnConnect != nil {
+			opts.Hooks.OnConnect(info)
+		}
+
+		reason := DisconnectClientClosed
+		var writeErr error
 		defer func() {
+			attrs := []any{"reason", string(reason), "duration", time.Since(info.ConnectedAt)}
+			if writeErr != nil {
+				attrs = append(attrs, "error", writeErr)
+			}
+			log.Log(ctx, opts.LogLevels.Connect.Level(), "client disconnected", attrs...)
 			if opts.Hooks.OnClientDisconnect != nil {
 				opts.Hooks.OnClientDisconnect(principal.ScopeID)
 			}
+			if opts.Hooks.OnDisconnect != nil {
+				opts.Hooks.OnDisconnect(info, reason)
+			}
 		}()
 
-		_, _ = fmt.Fprintf(w, ": retry %d\n\n", opts.RetryMilliseconds)
-		flusher.Flush()
+		// The stream ends when the credentials expire or the connection
+		// reaches its maximum lifetime, whichever comes first.
+		var deadline <-chan time.Time
+		deadlineReason := DisconnectLifetimeReached
+		if end := connectionEnd(info.ConnectedAt, principal, opts.MaxConnectionLifetime); !end.IsZero() {
+			if end.Equal(principal.ExpiresAt) {
+				deadlineReason = DisconnectAuthExpired
+			}
+			t := time.NewTimer(time.Until(end))
+			defer t.Stop()
+			deadline = t.C
+		}
+
+		stream := newStreamWriter(w, opts, opts.GzipStream && acceptsGzip(r))
+		defer stream.close()
+		if err := stream.send(func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, ": retry %d\n\n", opts.RetryMilliseconds)
+			return err
+		}); err != nil {
+			reason, writeErr = DisconnectWriteFailed, err
+			return
+		}
+
+		gapEvent := prefixEventName(opts.EventNamePrefix, GapEventType)
 
 		heartbeatTicker := time.NewTicker(opts.HeartbeatInterval)
 		defer heartbeatTicker.Stop()
 
 		for {
+			var err error
 			select {
 			case <-opts.Context.Done():
+				reason = DisconnectServerShutdown
 				return
-			case <-r.Context().Done():
+			case <-ctx.Done():
+				return
+			case <-deadline:
+				reason = deadlineReason
 				return
 			case <-heartbeatTicker.C:
-				_, _ = fmt.Fprintf(w, ": heartbeat\n\n")
-				flusher.Flush()
+				err = stream.send(func(w io.Writer) error {
+					_, err := fmt.Fprintf(w, ": heartbeat\n\n")
+					return err
+				})
 
-			case msg, ok := <-client.messageCh:
-				if !ok {
-					return
+			case <-client.done:
+				if r := client.closeReason(); r != "" {
+					reason = r
 				}
-				eventType, data, err := opts.EventEncoder(msg)
-				if err != nil {
-					if opts.Hooks.OnError != nil {
-						opts.Hooks.OnError(r.Context(), fmt.Errorf("failed to encode event: %w", err))
+				return
+			case <-client.notify:
+				err = stream.send(func(w io.Writer) error {
+					for {
+						msg, dropped, ok := client.next()
+						if dropped > 0 {
+							if err := writeSSE(w, "", gapEvent, []byte(fmt.Sprintf(`{"dropped":%d}`, dropped))); err != nil {
+								return err
+							}
+						}
+						if !ok {
+							return nil
+						}
+						if msg.expired(time.Now()) {
+							log.Log(ctx, opts.LogLevels.Drop.Level(), "event dropped", "reason", "expired")
+							if opts.Hooks.OnEventExpired != nil {
+								opts.Hooks.OnEventExpired(principal.ScopeID)
+							}
+							continue
+						}
+						eventType, data, err := encoder(msg.payload)
+						if err != nil {
+							log.ErrorContext(ctx, "failed to encode event", "error", err)
+							if opts.Hooks.OnError != nil {
+								opts.Hooks.OnError(ctx, fmt.Errorf("failed to encode event: %w", err))
+							}
+							continue
+						}
+						if err := writeSSE(w, msg.id, eventType, data); err != nil {
+							return err
+						}
 					}
-					continue
-				}
-				writeSSE(w, eventType, data)
-				flusher.Flush()
+				})
+			}
+			if err != nil {
+				reason, writeErr = DisconnectWriteFailed, err
+				return
+			}
+			if opts.SlowConsumer.exceedsWriteLatency(stream.latency) {
+				reason = DisconnectSlowConsumer
+				log.WarnContext(ctx, "slow consumer disconnected",
+					"reason", SlowConsumerWriteLatency, "write_latency_p95", stream.latency.p95())
+				opts.Hooks.slowConsumer(principal.ScopeID, info, SlowConsumerStats{
+					Reason:          SlowConsumerWriteLatency,
+					WriteLatencyP95: stream.latency.p95(),
+					Lag:             client.lag(time.Now()),
+					Samples:         stream.latency.n,
+				})
+				return
 			}
 		}
 	})
 }
 
-func writeSSE(w http.ResponseWriter, eventType string, data []byte) {
-	_, _ = fmt.Fprintf(w, "event: %s\n", eventType)
-	_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+func writeSSE(w io.Writer, id, eventType string, data []byte) error {
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\n", eventType); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// connectionEnd returns when a stream must close, or the zero time if never.
+func connectionEnd(connectedAt time.Time, p *Principal, lifetime time.Duration) time.Time {
+	var end time.Time
+	if lifetime > 0 {
+		end = connectedAt.Add(lifetime)
+	}
+	if !p.ExpiresAt.IsZero() && (end.IsZero() || p.ExpiresAt.Before(end)) {
+		end = p.ExpiresAt
+	}
+	return end
+}
+
+func requestLogger(r *http.Request, opts Options, logger *slog.Logger) *slog.Logger {
+	if opts.RequestLogger != nil {
+		return opts.RequestLogger(r, logger)
+	}
+	return logger
 }
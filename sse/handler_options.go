@@ -0,0 +1,70 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// StreamMiddleware runs before the principal is resolved. It may set
+// response headers, return a request carrying a derived context (nil keeps
+// r), or reject the stream by returning an error; a *StatusError chooses the
+// status, any other error is answered with 403.
+type StreamMiddleware func(w http.ResponseWriter, r *http.Request) (*http.Request, error)
+
+// ContextEventEncoder is an EventEncoder that also receives the stream
+// context, including values attached by StreamMiddleware.
+type ContextEventEncoder func(ctx context.Context, raw []byte) (eventtype string, data []byte, err error)
+
+type HandlerOptions struct {
+	// Middleware runs in order; the first error ends the request.
+	Middleware []StreamMiddleware
+	// Headers returns response headers for one stream, applied after
+	// Options.Headers.
+	Headers func(r *http.Request, p *Principal) http.Header
+	// EventEncoder replaces Options.EventEncoder for streams that did not
+	// negotiate a codec. EventNamePrefix still applies.
+	EventEncoder ContextEventEncoder
+}
+
+// StatusError rejects a stream request with an HTTP status. Err may be nil,
+// in which case the status text is the message. A Status outside 4xx and 5xx
+// is answered with 403.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.Status)
+	}
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Reject returns a *StatusError for StreamMiddleware.
+func Reject(status int, format string, args ...any) error {
+	return &StatusError{Status: status, Err: fmt.Errorf(format, args...)}
+}
+
+func applyStreamMiddleware(w http.ResponseWriter, r *http.Request, mws []StreamMiddleware) (*http.Request, int, error) {
+	for _, mw := range mws {
+		next, err := mw(w, r)
+		if err != nil {
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) && statusErr.Status >= 400 && statusErr.Status <= 599 {
+				return r, statusErr.Status, err
+			}
+			return r, http.StatusForbidden, err
+		}
+		if next != nil {
+			r = next
+		}
+	}
+	return r, 0, nil
+}
@@ -0,0 +1,127 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type requestIDKey struct{}
+
+func TestHandlerWithOptionsRejects(t *testing.T) {
+	resolved := false
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			resolved = true
+			return &Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router: func(*Principal) []string { return []string{"scope:1:*"} },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{Reject(http.StatusTooManyRequests, "slow down"), http.StatusTooManyRequests},
+		{&StatusError{Status: http.StatusTooManyRequests}, http.StatusTooManyRequests},
+		{&StatusError{Err: errors.New("no status")}, http.StatusForbidden},
+		{&StatusError{Status: http.StatusOK, Err: errors.New("not an error status")}, http.StatusForbidden},
+		{errors.New("nope"), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		handler := server.HandlerWithOptions(HandlerOptions{
+			Middleware: []StreamMiddleware{
+				func(w http.ResponseWriter, r *http.Request) (*http.Request, error) { return nil, tc.err },
+			},
+		})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != tc.want || !strings.Contains(rec.Body.String(), tc.err.Error()) {
+			t.Fatalf("unexpected response: %d %q", rec.Code, rec.Body.String())
+		}
+	}
+	if resolved {
+		t.Fatal("expected rejected requests not to resolve a principal")
+	}
+}
+
+func TestHandlerWithOptionsEnrichesStream(t *testing.T) {
+	connected := make(chan ConnInfo, 1)
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 7, ScopeID: 1}, nil
+		}),
+		Router:          func(*Principal) []string { return []string{"scope:1:*"} },
+		EventNamePrefix: "app",
+		Hooks:           Hooks{OnConnect: func(info ConnInfo) { connected <- info }},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(server.HandlerWithOptions(HandlerOptions{
+		Middleware: []StreamMiddleware{
+			func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+				id := r.Header.Get("X-Request-ID")
+				w.Header().Set("X-Request-ID", id)
+				return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)), nil
+			},
+		},
+		Headers: func(_ *http.Request, p *Principal) http.Header {
+			return http.Header{"X-User": {"7"}, "Cache-Control": {"private"}}
+		},
+		EventEncoder: func(ctx context.Context, raw []byte) (string, []byte, error) {
+			return "tagged", []byte(`"` + ctx.Value(requestIDKey{}).(string) + `"`), nil
+		},
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("X-Request-ID", "req-9")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("X-Request-ID") != "req-9" || resp.Header.Get("X-User") != "7" ||
+		resp.Header.Get("Cache-Control") != "private" {
+		t.Fatalf("unexpected headers: %v", resp.Header)
+	}
+
+	select {
+	case info := <-connected:
+		if info.Context.Value(requestIDKey{}) != "req-9" {
+			t.Fatalf("expected request id in conn context, got %v", info.Context.Value(requestIDKey{}))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for OnConnect")
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	for i := 0; i < 2; i++ {
+		if _, err := readLineWithTimeout(reader, time.Second); err != nil {
+			t.Fatalf("failed to read retry line: %v", err)
+		}
+	}
+	if err := server.Publisher().PublishEvent(context.Background(), "scope:1:students", Event{EventType: "students.changed"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	line, err := readLineWithTimeout(reader, time.Second)
+	if err != nil || line != "event: app.tagged" {
+		t.Fatalf("unexpected event line: %q, %v", line, err)
+	}
+	line, err = readLineWithTimeout(reader, time.Second)
+	if err != nil || line != `data: "req-9"` {
+		t.Fatalf("unexpected data line: %q, %v", line, err)
+	}
+}
@@ -89,3 +89,24 @@ func readLineWithTimeout(reader *bufio.Reader, timeout time.Duration) (string, e
 		return "", context.DeadlineExceeded
 	}
 }
+
+func TestSSEHandlerRejectsUnknownCodec(t *testing.T) {
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router: func(*Principal) []string { return []string{"scope:1:*"} },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(CodecHeader, "xml")
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
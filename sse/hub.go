@@ -2,14 +2,13 @@ package sse
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
 	"sync"
 	"time"
 )
 
-type client struct {
-	messageCh chan []byte
-}
-
 type Hub struct {
 	scopeID  int64
 	patterns []string
@@ -17,6 +16,7 @@ type Hub struct {
 	broker Broker
 	opts   Options
 	ctx    context.Context
+	log    *slog.Logger
 
 	mu         sync.RWMutex
 	clients    map[*client]struct{}
@@ -25,6 +25,9 @@ type Hub struct {
 	sub     Subscription
 	cancel  context.CancelFunc
 	running bool
+
+	dedup *dedupWindow
+	seqs  map[string]uint64
 }
 
 func newHub(ctx context.Context, broker Broker, options Options, scopeID int64, patterns []string) *Hub {
@@ -34,16 +37,20 @@ func newHub(ctx context.Context, broker Broker, options Options, scopeID int64,
 		broker:     broker,
 		opts:       options,
 		ctx:        ctx,
+		log:        options.Logger.With("scope_id", scopeID),
 		clients:    make(map[*client]struct{}),
 		lastActive: time.Now(),
+		dedup:      newDedupWindow(options.DedupWindow),
+		seqs:       make(map[string]uint64),
 	}
 }
 
-func (h *Hub) addClient(buf int) *client {
+func (h *Hub) addClient(buf int, info ConnInfo) *client {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	c := &client{messageCh: make(chan []byte, buf)}
+	c := newClient(buf)
+	c.info = info
 
 	h.clients[c] = struct{}{}
 	h.lastActive = time.Now()
@@ -60,7 +67,7 @@ func (h *Hub) removeClient(c *client) {
 	defer h.mu.Unlock()
 
 	if _, exists := h.clients[c]; exists {
-		close(c.messageCh)
+		c.close()
 		delete(h.clients, c)
 		h.lastActive = time.Now()
 	}
@@ -75,6 +82,7 @@ func (h *Hub) start() {
 	if err != nil {
 		h.running = false
 		h.cancel = nil
+		h.log.ErrorContext(ctx, "broker subscribe failed", "patterns", h.patterns, "error", err)
 		if h.opts.Hooks.OnError != nil {
 			h.opts.Hooks.OnError(ctx, err)
 		}
@@ -82,6 +90,7 @@ func (h *Hub) start() {
 	}
 
 	h.sub = sub
+	h.log.DebugContext(ctx, "hub started", "patterns", h.patterns)
 
 	if h.opts.Hooks.OnHubStarted != nil {
 		h.opts.Hooks.OnHubStarted(h.scopeID, h.patterns)
@@ -108,57 +117,239 @@ func (h *Hub) run(ctx context.Context, sub Subscription) {
 			return
 		case msg, ok := <-sub.Channel():
 			if !ok {
-				return
+				h.log.WarnContext(ctx, "broker subscription closed", "patterns", h.patterns)
+				_ = sub.Close()
+				if sub = h.resubscribe(ctx, sub); sub == nil {
+					return
+				}
+				continue
+			}
+			h.broadcast(msg)
+		}
+	}
+}
+
+// resubscribe replaces a subscription the broker closed, retrying every
+// ResubscribeInterval while the hub has clients. It returns nil once the
+// hub is stopped or empty.
+func (h *Hub) resubscribe(ctx context.Context, old Subscription) Subscription {
+	for {
+		h.mu.Lock()
+		if h.sub != old {
+			h.mu.Unlock()
+			return nil
+		}
+		if len(h.clients) == 0 {
+			h.running = false
+			h.sub = nil
+			h.cancel = nil
+			h.mu.Unlock()
+			return nil
+		}
+		h.mu.Unlock()
+
+		sub, err := h.broker.Subscribe(ctx, h.patterns...)
+		if err == nil {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.sub != old {
+				_ = sub.Close()
+				return nil
 			}
-			h.broadcast(msg.Payload)
+			h.sub = sub
+			h.log.InfoContext(ctx, "broker resubscribed", "patterns", h.patterns)
+			return sub
+		}
+
+		h.log.ErrorContext(ctx, "broker resubscribe failed", "patterns", h.patterns,
+			"error", err, "retry_in", h.opts.ResubscribeInterval)
+		if h.opts.Hooks.OnError != nil {
+			h.opts.Hooks.OnError(ctx, err)
+		}
+
+		t := time.NewTimer(h.opts.ResubscribeInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
 		}
 	}
 }
 
-func (h *Hub) broadcast(payload []byte) {
+func (h *Hub) broadcast(msg BrokerMsg) {
+	if msg.Dropped > 0 {
+		h.log.WarnContext(h.ctx, "broker dropped messages", "pattern", msg.Pattern, "dropped", msg.Dropped)
+		h.markGap(msg.Dropped)
+	}
+
+	seq, payload, err := splitSequence(msg.Payload)
+	if err != nil {
+		h.log.WarnContext(h.ctx, "payload rejected", "channel", msg.Channel, "error", err)
+		if h.opts.Hooks.OnError != nil {
+			h.opts.Hooks.OnError(h.ctx, err)
+		}
+		return
+	}
+	msg.Payload = payload
+
+	if h.opts.Keyring != nil {
+		payload, err := h.opts.Keyring.open(msg.Channel, msg.Payload)
+		if err != nil {
+			h.log.WarnContext(h.ctx, "payload rejected", "channel", msg.Channel, "error", err)
+			if h.opts.Hooks.OnError != nil {
+				h.opts.Hooks.OnError(h.ctx, err)
+			}
+			return
+		}
+		msg.Payload = payload
+	}
+
+	payload, err = decompressPayload(msg.Payload)
+	if err != nil {
+		h.log.ErrorContext(h.ctx, "failed to decompress payload", "channel", msg.Channel, "error", err)
+		if h.opts.Hooks.OnError != nil {
+			h.opts.Hooks.OnError(h.ctx, fmt.Errorf("failed to decompress payload: %w", err))
+		}
+		return
+	}
+	msg.Payload = payload
+
+	now := time.Now()
+	qm := queuedMsg{payload: msg.Payload, enqueuedAt: now}
+
+	var evt Event
+	if err := h.opts.Codec.Unmarshal(msg.Payload, &evt); err == nil {
+		if seq == 0 {
+			seq = evt.Sequence
+		}
+		if seq > 0 {
+			qm.id = msg.Channel + ":" + strconv.FormatUint(seq, 10)
+		}
+		if missed := h.trackSequence(msg.Channel, seq); missed > 0 {
+			h.markGap(missed)
+		}
+		if evt.expired(now) {
+			h.logDrop(msg.Channel, evt.EventType, "expired")
+			if h.opts.Hooks.OnEventExpired != nil {
+				h.opts.Hooks.OnEventExpired(h.scopeID)
+			}
+			return
+		}
+		qm.expiresAt = evt.ExpiresAt
+		if evt.ID != "" && h.dedup != nil && h.dedup.seenBefore(dedupKey(msg.Channel, evt.ID)) {
+			h.logDrop(msg.Channel, evt.EventType, "duplicate")
+			if h.opts.Hooks.OnDuplicateEvent != nil {
+				h.opts.Hooks.OnDuplicateEvent(h.scopeID)
+			}
+			return
+		}
+	}
+	if h.opts.Backpressure == BackpressureCoalesce {
+		qm.key = h.coalesceKey(msg)
+	}
+
 	var toRemove []*client
 	n := 0
 
 	h.mu.RLock()
-	switch h.opts.Backpressure {
-	case BackpressureDrop:
-		for c := range h.clients {
-			select {
-			case c.messageCh <- payload:
-				n++
-			default:
-				if h.opts.Hooks.OnClientDropped != nil {
-					h.opts.Hooks.OnClientDropped(h.scopeID, "backpressure drop")
-				}
-			}
-		}
-	case BackpressureDisconnect:
-		for c := range h.clients {
-			select {
-			case c.messageCh <- payload:
-				n++
-			default:
+	for c := range h.clients {
+		if maxLag := h.opts.SlowConsumer.MaxLag; maxLag > 0 {
+			if lag := c.lag(now); lag > maxLag {
+				c.closeWith(DisconnectSlowConsumer)
 				toRemove = append(toRemove, c)
+				h.log.WarnContext(h.ctx, "slow consumer disconnected",
+					"conn_id", c.info.ID, "reason", SlowConsumerLag, "lag", lag)
+				h.opts.Hooks.slowConsumer(h.scopeID, c.info, SlowConsumerStats{Reason: SlowConsumerLag, Lag: lag})
+				continue
 			}
 		}
+		accepted, discarded := c.enqueue(qm, h.opts.Backpressure)
+		if accepted {
+			n++
+		}
+		switch {
+		case !accepted && h.opts.Backpressure == BackpressureDisconnect:
+			c.closeWith(DisconnectBackpressure)
+			toRemove = append(toRemove, c)
+			h.logDrop(msg.Channel, evt.EventType, "backpressure disconnect")
+		case !accepted:
+			h.logDrop(msg.Channel, evt.EventType, "backpressure drop")
+			h.opts.Hooks.clientDropped(h.scopeID, c.info, "backpressure drop")
+		case discarded:
+			h.logDrop(msg.Channel, evt.EventType, "backpressure drop oldest")
+			h.opts.Hooks.clientDropped(h.scopeID, c.info, "backpressure drop oldest")
+		}
 	}
 	h.mu.RUnlock()
 
 	h.mu.Lock()
 	for _, c := range toRemove {
 		if _, exists := h.clients[c]; exists {
-			close(c.messageCh)
+			c.close()
 			delete(h.clients, c)
 		}
 	}
 	h.lastActive = time.Now()
 	h.mu.Unlock()
 
+	h.log.DebugContext(h.ctx, "event broadcast", "channel", msg.Channel, "event_type", evt.EventType, "clients", n)
 	if h.opts.Hooks.OnEventBroadcast != nil {
 		h.opts.Hooks.OnEventBroadcast(h.scopeID, n)
 	}
 }
 
+func (h *Hub) logDrop(channel, eventType, reason string) {
+	h.log.Log(h.ctx, h.opts.LogLevels.Drop.Level(), "event dropped",
+		"channel", channel, "event_type", eventType, "reason", reason)
+}
+
+// trackSequence records seq as the latest for channel and returns how many
+// sequence numbers were skipped since the previous message. A sequence lower
+// than the last one seen is treated as a counter reset.
+func (h *Hub) trackSequence(channel string, seq uint64) int {
+	if seq == 0 {
+		return 0
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	last, seen := h.seqs[channel]
+	h.seqs[channel] = seq
+	if !seen || seq <= last {
+		return 0
+	}
+	missed := seq - last - 1
+	if missed == 0 {
+		return 0
+	}
+	h.log.WarnContext(h.ctx, "sequence gap", "channel", channel, "missed", missed)
+	if h.opts.Hooks.OnSequenceGap != nil {
+		h.opts.Hooks.OnSequenceGap(h.scopeID, channel, missed)
+	}
+	return int(missed)
+}
+
+func (h *Hub) markGap(missed int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		c.markGap(missed)
+	}
+}
+
+// coalesceKey identifies messages that supersede each other in a client
+// queue: the same event type published on the same channel.
+func (h *Hub) coalesceKey(msg BrokerMsg) string {
+	eventType, _, err := h.opts.EventEncoder(msg.Payload)
+	if err != nil {
+		return ""
+	}
+	return msg.Channel + "\x00" + eventType
+}
+
 func (h *Hub) stop() {
 	h.mu.Lock()
 	cancel := h.cancel
@@ -168,7 +359,7 @@ func (h *Hub) stop() {
 	h.sub = nil
 
 	for c := range h.clients {
-		close(c.messageCh)
+		c.closeWith(DisconnectServerShutdown)
 		delete(h.clients, c)
 	}
 	h.mu.Unlock()
@@ -180,6 +371,7 @@ func (h *Hub) stop() {
 		_ = sub.Close()
 	}
 
+	h.log.DebugContext(h.ctx, "hub stopped")
 	if h.opts.Hooks.OnHubStopped != nil {
 		h.opts.Hooks.OnHubStopped(h.scopeID)
 	}
@@ -40,6 +40,7 @@ func (hm *hubManager) getOrCreateHub(scopeID int64, patterns []string) *Hub {
 	if !exists {
 		hub = newHub(hm.ctx, hm.broker, hm.opts, scopeID, patterns)
 		hm.hubs[scopeID] = hub
+		hm.opts.Logger.DebugContext(hm.ctx, "hub created", "scope_id", scopeID, "hubs", len(hm.hubs))
 	}
 
 	return hub
@@ -66,6 +67,7 @@ func (hm *hubManager) reaper() {
 			hm.mu.Unlock()
 
 			for _, hub := range idle {
+				hm.opts.Logger.DebugContext(hm.ctx, "idle hub reaped", "scope_id", hub.scopeID)
 				hub.stop()
 			}
 		}
@@ -83,6 +85,7 @@ func (hm *hubManager) stopAll() {
 	}
 	hm.mu.Unlock()
 
+	hm.opts.Logger.DebugContext(hm.ctx, "stopping hubs", "hubs", len(hubs))
 	for _, hub := range hubs {
 		hub.stop()
 	}
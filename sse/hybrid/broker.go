@@ -0,0 +1,201 @@
+// Package hybrid implements a local-first sse.Broker. Publishes are delivered
+// to subscriptions of the same process immediately and forwarded to a remote
+// broker tagged with the instance ID; each instance drops its own echo when
+// it comes back from the remote broker.
+//
+// The tag is part of the remote payload, so every process subscribing to the
+// remote broker must use this package to read it. Messages keep their order
+// per source, but local and remote messages interleave in arrival order.
+package hybrid
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/PabloPavan/eventrail/sse/memory"
+)
+
+// tagPrefix starts every payload forwarded to the remote broker. Payloads
+// without it, such as those published by other languages, are delivered
+// unchanged.
+const tagPrefix = "\x00eventrail:"
+
+type BrokerOptions struct {
+	// InstanceID identifies this process on the remote broker. Default is a
+	// random ID. It cannot contain newlines.
+	InstanceID string
+	// Logger, DropLevel and OnError configure the local broker; see
+	// memory.BrokerInMemoryOptions. Drops on the remote side are reported by
+	// the remote broker's own options.
+	Logger    *slog.Logger
+	DropLevel slog.Leveler
+	OnError   func(ctx context.Context, err error)
+}
+
+type Broker struct {
+	local  *memory.BrokerInMemory
+	remote sse.Broker
+	tag    []byte
+}
+
+func NewBroker(remote sse.Broker) (*Broker, error) {
+	return NewBrokerWithOptions(remote, BrokerOptions{})
+}
+
+func NewBrokerWithOptions(remote sse.Broker, options BrokerOptions) (*Broker, error) {
+	if remote == nil {
+		return nil, errors.New("remote broker cannot be nil")
+	}
+	if options.InstanceID == "" {
+		var id [8]byte
+		_, _ = rand.Read(id[:])
+		options.InstanceID = hex.EncodeToString(id[:])
+	}
+	if strings.ContainsRune(options.InstanceID, '\n') {
+		return nil, errors.New("instance id cannot contain newlines")
+	}
+
+	return &Broker{
+		local: memory.NewBrokerInMemoryWithOptions(memory.BrokerInMemoryOptions{
+			Logger:    options.Logger,
+			DropLevel: options.DropLevel,
+			OnError:   options.OnError,
+		}),
+		remote: remote,
+		tag:    []byte(tagPrefix + options.InstanceID + "\n"),
+	}, nil
+}
+
+// Publish delivers payload to local subscriptions, then forwards it to the
+// remote broker. Local delivery happens even if forwarding fails.
+func (b *Broker) Publish(ctx context.Context, channel string, payload []byte) error {
+	if err := b.local.Publish(ctx, channel, payload); err != nil {
+		return err
+	}
+
+	tagged := make([]byte, 0, len(b.tag)+len(payload))
+	tagged = append(tagged, b.tag...)
+	tagged = append(tagged, payload...)
+	return b.remote.Publish(ctx, channel, tagged)
+}
+
+// PublishSequenced forwards to the remote broker, which must implement
+// sse.Sequencer. Numbered messages skip local delivery and reach local
+// subscribers through the remote broker, so they arrive in sequence order.
+func (b *Broker) PublishSequenced(ctx context.Context, channel string, payload []byte) error {
+	seq, ok := b.remote.(sse.Sequencer)
+	if !ok {
+		return errors.New("remote broker does not implement sse.Sequencer")
+	}
+	return seq.PublishSequenced(ctx, channel, payload)
+}
+
+func (b *Broker) Subscribe(ctx context.Context, patterns ...string) (sse.Subscription, error) {
+	local, err := b.local.Subscribe(ctx, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := b.remote.Subscribe(ctx, patterns...)
+	if err != nil {
+		_ = local.Close()
+		return nil, err
+	}
+
+	sub := &subscription{
+		local:  local,
+		remote: remote,
+		out:    make(chan sse.BrokerMsg, 128),
+		done:   make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sub.forward(local.Channel(), nil)
+	}()
+	go func() {
+		defer wg.Done()
+		sub.forward(remote.Channel(), b.untag)
+	}()
+	go func() {
+		wg.Wait()
+		close(sub.out)
+	}()
+
+	return sub, nil
+}
+
+// untag strips the instance tag from a remote message, reporting false for
+// messages this instance published itself.
+func (b *Broker) untag(msg sse.BrokerMsg) (sse.BrokerMsg, bool) {
+	if !bytes.HasPrefix(msg.Payload, []byte(tagPrefix)) {
+		return msg, true
+	}
+	if bytes.HasPrefix(msg.Payload, b.tag) {
+		return msg, false
+	}
+	if i := bytes.IndexByte(msg.Payload, '\n'); i >= 0 {
+		msg.Payload = msg.Payload[i+1:]
+	}
+	return msg, true
+}
+
+type subscription struct {
+	local  sse.Subscription
+	remote sse.Subscription
+	out    chan sse.BrokerMsg
+	done   chan struct{}
+	once   sync.Once
+}
+
+// forward copies messages from in to out until in closes. Either side
+// closing ends the whole subscription, since a hub must not keep running on
+// half of its messages.
+func (s *subscription) forward(in <-chan sse.BrokerMsg, filter func(sse.BrokerMsg) (sse.BrokerMsg, bool)) {
+	defer func() { _ = s.Close() }()
+
+	// dropped carries the drop count of filtered echoes to the next message.
+	var dropped int
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg, ok := <-in:
+			if !ok {
+				return
+			}
+			if filter != nil {
+				if msg, ok = filter(msg); !ok {
+					dropped += msg.Dropped
+					continue
+				}
+			}
+			msg.Dropped += dropped
+			select {
+			case s.out <- msg:
+				dropped = 0
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+func (s *subscription) Channel() <-chan sse.BrokerMsg { return s.out }
+
+func (s *subscription) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = errors.Join(s.local.Close(), s.remote.Close())
+	})
+	return err
+}
@@ -0,0 +1,179 @@
+package hybrid
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/PabloPavan/eventrail/sse/memory"
+)
+
+func collect(sub sse.Subscription) []string {
+	var got []string
+	for {
+		select {
+		case msg := <-sub.Channel():
+			got = append(got, string(msg.Payload))
+		case <-time.After(100 * time.Millisecond):
+			return got
+		}
+	}
+}
+
+func TestBrokerDeliversOnceAcrossInstances(t *testing.T) {
+	remote := memory.NewBrokerInMemory()
+	a, err := NewBrokerWithOptions(remote, BrokerOptions{InstanceID: "a"})
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+	b, err := NewBrokerWithOptions(remote, BrokerOptions{InstanceID: "b"})
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subA, err := a.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	subB, err := b.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	if err := a.Publish(context.Background(), "scope:1:students", []byte("hello")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	if got := collect(subA); len(got) != 1 || got[0] != "hello" {
+		t.Fatalf("expected one local delivery without echo, got %v", got)
+	}
+	if got := collect(subB); len(got) != 1 || got[0] != "hello" {
+		t.Fatalf("expected one remote delivery with tag stripped, got %v", got)
+	}
+}
+
+func TestBrokerDeliversUntaggedRemoteMessages(t *testing.T) {
+	remote := memory.NewBrokerInMemory()
+	broker, err := NewBroker(remote)
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	if err := remote.Publish(context.Background(), "scope:1:students", []byte(`{"event_type":"x"}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if got := collect(sub); len(got) != 1 || got[0] != `{"event_type":"x"}` {
+		t.Fatalf("unexpected delivery: %v", got)
+	}
+}
+
+func TestBrokerCloseClosesChannel(t *testing.T) {
+	broker, err := NewBroker(memory.NewBrokerInMemory())
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-sub.Channel():
+		if ok {
+			t.Fatal("expected channel to be closed")
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout waiting for channel close")
+	}
+}
+
+func TestNewBrokerValidation(t *testing.T) {
+	if _, err := NewBroker(nil); err == nil {
+		t.Fatal("expected error for nil remote")
+	}
+	if _, err := NewBrokerWithOptions(memory.NewBrokerInMemory(), BrokerOptions{InstanceID: "a\nb"}); err == nil {
+		t.Fatal("expected error for instance id with newline")
+	}
+}
+
+// plainBroker hides everything but the sse.Broker methods.
+type plainBroker struct{ sse.Broker }
+
+func TestBrokerPublishSequencedGoesThroughRemote(t *testing.T) {
+	broker, err := NewBroker(memory.NewBrokerInMemory())
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	for _, p := range []string{"a", "b"} {
+		if err := broker.PublishSequenced(context.Background(), "scope:1:students", []byte(p)); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	got := collect(sub)
+	want := []string{string(sse.SequencePayload(1, []byte("a"))), string(sse.SequencePayload(2, []byte("b")))}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("expected %q once each in order, got %q", want, got)
+	}
+
+	noSeq, err := NewBroker(plainBroker{memory.NewBrokerInMemory()})
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+	if err := noSeq.PublishSequenced(context.Background(), "scope:1:students", nil); err == nil {
+		t.Fatal("expected error for remote broker without Sequencer")
+	}
+}
+
+func TestBrokerCarriesDropsPastEchoes(t *testing.T) {
+	b, err := NewBrokerWithOptions(memory.NewBrokerInMemory(), BrokerOptions{InstanceID: "a"})
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	in := make(chan sse.BrokerMsg, 2)
+	in <- sse.BrokerMsg{Payload: append(append([]byte(nil), b.tag...), "echo"...), Dropped: 2}
+	in <- sse.BrokerMsg{Payload: []byte("other"), Dropped: 1}
+	close(in)
+
+	sub := &subscription{
+		local:  noopSubscription{},
+		remote: noopSubscription{},
+		out:    make(chan sse.BrokerMsg, 2),
+		done:   make(chan struct{}),
+	}
+	sub.forward(in, b.untag)
+
+	if msg := <-sub.out; string(msg.Payload) != "other" || msg.Dropped != 3 {
+		t.Fatalf("expected the echo's drops with the next message, got %q, %d", msg.Payload, msg.Dropped)
+	}
+}
+
+type noopSubscription struct{}
+
+func (noopSubscription) Channel() <-chan sse.BrokerMsg { return nil }
+func (noopSubscription) Close() error                  { return nil }
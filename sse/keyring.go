@@ -0,0 +1,154 @@
+package sse
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+var ErrForgedPayload = errors.New("forged payload")
+
+// Keyring signs, and optionally encrypts, broker payloads so that only
+// holders of a key can read or inject events. Signatures cover the channel,
+// so a sealed payload cannot be replayed onto another channel.
+type Keyring struct {
+	// ActiveKeyID selects the key used for new payloads.
+	ActiveKeyID string
+	// Keys maps key IDs to secrets of at least 32 bytes. Keep a retired key
+	// until payloads sealed with it can no longer arrive.
+	Keys map[string][]byte
+	// Encrypt AES-GCM encrypts payloads in addition to signing them.
+	Encrypt bool
+}
+
+// sealedMarker prefixes sealed payloads and is followed by a mode byte, the
+// key ID length and the key ID.
+const sealedMarker = "\x00k"
+
+const (
+	modeSigned    = 's'
+	modeEncrypted = 'e'
+)
+
+const minKeySize = 32
+
+func (k *Keyring) validate() error {
+	if _, ok := k.Keys[k.ActiveKeyID]; !ok {
+		return fmt.Errorf("active key %q not found in keyring", k.ActiveKeyID)
+	}
+	for id, secret := range k.Keys {
+		if id == "" || len(id) > 255 {
+			return fmt.Errorf("key id %q must be 1-255 bytes", id)
+		}
+		if len(secret) < minKeySize {
+			return fmt.Errorf("key %q must be at least %d bytes", id, minKeySize)
+		}
+	}
+	return nil
+}
+
+func (k *Keyring) seal(channel string, payload []byte) ([]byte, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	secret := k.Keys[k.ActiveKeyID]
+
+	mode := byte(modeSigned)
+	if k.Encrypt {
+		mode = modeEncrypted
+	}
+	var buf bytes.Buffer
+	buf.WriteString(sealedMarker)
+	buf.WriteByte(mode)
+	buf.WriteByte(byte(len(k.ActiveKeyID)))
+	buf.WriteString(k.ActiveKeyID)
+
+	body := payload
+	if k.Encrypt {
+		gcm, err := newGCM(secret)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		body = gcm.Seal(nonce, nonce, payload, []byte(channel))
+	}
+	buf.Write(body)
+	buf.Write(signature(secret, channel, buf.Bytes()))
+	return buf.Bytes(), nil
+}
+
+// open verifies and, if needed, decrypts a sealed payload published on
+// channel. Unsigned payloads are rejected.
+func (k *Keyring) open(channel string, sealed []byte) ([]byte, error) {
+	header := len(sealedMarker) + 2
+	if !bytes.HasPrefix(sealed, []byte(sealedMarker)) || len(sealed) < header {
+		return nil, fmt.Errorf("%w: payload on %s is not signed", ErrForgedPayload, channel)
+	}
+	mode, idLen := sealed[len(sealedMarker)], int(sealed[len(sealedMarker)+1])
+	if len(sealed) < header+idLen+sha256.Size {
+		return nil, fmt.Errorf("%w: truncated payload on %s", ErrForgedPayload, channel)
+	}
+	keyID := string(sealed[header : header+idLen])
+	secret, ok := k.Keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q on %s", ErrForgedPayload, keyID, channel)
+	}
+
+	signed, mac := sealed[:len(sealed)-sha256.Size], sealed[len(sealed)-sha256.Size:]
+	if !hmac.Equal(mac, signature(secret, channel, signed)) {
+		return nil, fmt.Errorf("%w: bad signature on %s", ErrForgedPayload, channel)
+	}
+
+	body := signed[header+idLen:]
+	switch mode {
+	case modeSigned:
+		return body, nil
+	case modeEncrypted:
+		gcm, err := newGCM(secret)
+		if err != nil {
+			return nil, err
+		}
+		if len(body) < gcm.NonceSize() {
+			return nil, fmt.Errorf("%w: truncated payload on %s", ErrForgedPayload, channel)
+		}
+		plain, err := gcm.Open(nil, body[:gcm.NonceSize()], body[gcm.NonceSize():], []byte(channel))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v on %s", ErrForgedPayload, err, channel)
+		}
+		return plain, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q on %s", ErrForgedPayload, mode, channel)
+	}
+}
+
+// signature is the HMAC-SHA256 of channel and data under a key derived from
+// secret, kept separate from the encryption key.
+func signature(secret []byte, channel string, data []byte) []byte {
+	mac := hmac.New(sha256.New, deriveKey(secret, "sign"))
+	mac.Write([]byte(channel))
+	mac.Write([]byte{0})
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func newGCM(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(secret, "encrypt"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("eventrail " + purpose))
+	return mac.Sum(nil)
+}
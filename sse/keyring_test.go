@@ -0,0 +1,138 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+var (
+	testKeyOld = bytes.Repeat([]byte{1}, 32)
+	testKeyNew = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestKeyringSealOpen(t *testing.T) {
+	payload := []byte(`{"event_type":"students.changed","data":{"name":"secret"}}`)
+
+	for _, encrypt := range []bool{false, true} {
+		k := &Keyring{ActiveKeyID: "k1", Keys: map[string][]byte{"k1": testKeyOld}, Encrypt: encrypt}
+
+		sealed, err := k.seal("scope:1:students", payload)
+		if err != nil {
+			t.Fatalf("seal failed: %v", err)
+		}
+		if encrypt == bytes.Contains(sealed, []byte("secret")) {
+			t.Fatalf("encrypt=%v: unexpected plaintext visibility in %q", encrypt, sealed)
+		}
+
+		got, err := k.open("scope:1:students", sealed)
+		if err != nil {
+			t.Fatalf("open failed: %v", err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Fatalf("unexpected payload: %q", got)
+		}
+	}
+}
+
+func TestKeyringRejectsForgedPayloads(t *testing.T) {
+	k := &Keyring{ActiveKeyID: "k1", Keys: map[string][]byte{"k1": testKeyOld}, Encrypt: true}
+	sealed, err := k.seal("scope:1:students", []byte(`{"event_type":"x"}`))
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)/2] ^= 0xff
+	other := &Keyring{ActiveKeyID: "k1", Keys: map[string][]byte{"k1": testKeyNew}}
+
+	cases := map[string]func() ([]byte, error){
+		"unsigned":      func() ([]byte, error) { return k.open("scope:1:students", []byte(`{"event_type":"x"}`)) },
+		"tampered":      func() ([]byte, error) { return k.open("scope:1:students", tampered) },
+		"other channel": func() ([]byte, error) { return k.open("scope:2:students", sealed) },
+		"wrong secret":  func() ([]byte, error) { return other.open("scope:1:students", sealed) },
+		"truncated":     func() ([]byte, error) { return k.open("scope:1:students", sealed[:5]) },
+	}
+	for name, open := range cases {
+		if _, err := open(); !errors.Is(err, ErrForgedPayload) {
+			t.Fatalf("%s: expected ErrForgedPayload, got %v", name, err)
+		}
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old := &Keyring{ActiveKeyID: "k1", Keys: map[string][]byte{"k1": testKeyOld}}
+	sealed, err := old.seal("scope:1:students", []byte("payload"))
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+
+	rotated := &Keyring{ActiveKeyID: "k2", Keys: map[string][]byte{"k1": testKeyOld, "k2": testKeyNew}}
+	if _, err := rotated.open("scope:1:students", sealed); err != nil {
+		t.Fatalf("expected retired key to verify, got %v", err)
+	}
+
+	retired := &Keyring{ActiveKeyID: "k2", Keys: map[string][]byte{"k2": testKeyNew}}
+	if _, err := retired.open("scope:1:students", sealed); !errors.Is(err, ErrForgedPayload) {
+		t.Fatalf("expected removed key to be rejected, got %v", err)
+	}
+}
+
+func TestNewServerValidatesKeyring(t *testing.T) {
+	for _, k := range []*Keyring{
+		{ActiveKeyID: "missing", Keys: map[string][]byte{"k1": testKeyOld}},
+		{ActiveKeyID: "k1", Keys: map[string][]byte{"k1": []byte("short")}},
+	} {
+		_, err := NewServer(newTestBroker(), Options{
+			Resolver: resolverFunc(func(*http.Request) (*Principal, error) { return &Principal{}, nil }),
+			Router:   func(*Principal) []string { return nil },
+			Keyring:  k,
+		})
+		if err == nil {
+			t.Fatalf("expected error for keyring %+v", k)
+		}
+	}
+}
+
+func TestHubDropsForgedPayloads(t *testing.T) {
+	var errs []error
+	keyring := &Keyring{ActiveKeyID: "k1", Keys: map[string][]byte{"k1": testKeyOld}}
+	opts := Options{
+		Keyring: keyring,
+		Hooks:   Hooks{OnError: func(_ context.Context, err error) { errs = append(errs, err) }},
+	}
+	applyDefaultOptions(&opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := newTestBroker()
+	hub := newHub(ctx, broker, opts, 1, []string{"scope:1:*"})
+	c := hub.addClient(4, ConnInfo{})
+	defer hub.stop()
+
+	pub := NewPublisherWithOptions(broker, PublisherOptions{Keyring: keyring})
+	payload, _, err := pub.encode(context.Background(), "scope:1:students", Event{EventType: "students.changed"})
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: []byte(`{"event_type":"injected"}`)})
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: payload})
+
+	if len(errs) != 1 || !errors.Is(errs[0], ErrForgedPayload) {
+		t.Fatalf("expected one forged payload error, got %v", errs)
+	}
+	msg, _, ok := c.next()
+	if !ok {
+		t.Fatal("expected signed event to be delivered")
+	}
+	if eventType, _, err := opts.EventEncoder(msg.payload); err != nil || eventType != "students.changed" {
+		t.Fatalf("unexpected delivered event: %q, %v", eventType, err)
+	}
+	if _, _, ok := c.next(); ok {
+		t.Fatal("expected forged event to be dropped")
+	}
+}
@@ -0,0 +1,20 @@
+package sse
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// LogLevels sets the level of log records that can be noisy under load.
+type LogLevels struct {
+	// Drop applies to events dropped by backpressure, expiry or duplicate
+	// suppression. Default debug.
+	Drop slog.Leveler
+	// Connect applies to client connects and disconnects. Default info.
+	Connect slog.Leveler
+}
+
+// RequestLogger derives the logger used for one SSE connection, for example
+// to add a request or trace ID. logger already carries scope_id, user_id and
+// conn_id.
+type RequestLogger func(r *http.Request, logger *slog.Logger) *slog.Logger
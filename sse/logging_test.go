@@ -0,0 +1,106 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHubLogsDropsAtConfiguredLevel(t *testing.T) {
+	var out syncBuffer
+	opts := Options{
+		Logger:    slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelWarn})),
+		LogLevels: LogLevels{Drop: slog.LevelWarn},
+	}
+	applyDefaultOptions(&opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := newHub(ctx, newTestBroker(), opts, 7, []string{"scope:7:*"})
+	hub.addClient(1, ConnInfo{})
+	defer hub.stop()
+
+	hub.broadcast(BrokerMsg{Channel: "scope:7:students", Payload: []byte(`{"event_type":"a"}`)})
+	hub.broadcast(BrokerMsg{Channel: "scope:7:students", Payload: []byte(`{"event_type":"b"}`)})
+
+	logs := out.String()
+	for _, want := range []string{`msg="event dropped"`, "scope_id=7", "channel=scope:7:students", "event_type=b", `reason="backpressure drop"`} {
+		if !strings.Contains(logs, want) {
+			t.Fatalf("expected %q in logs:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "event broadcast") {
+		t.Fatalf("expected debug records to be filtered:\n%s", logs)
+	}
+}
+
+func TestSSEHandlerLogsConnectionLifecycle(t *testing.T) {
+	var out syncBuffer
+	server, err := NewServer(newTestBroker(), Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 42, ScopeID: 1}, nil
+		}),
+		Router: func(*Principal) []string { return []string{"scope:1:*"} },
+		Logger: slog.New(slog.NewTextHandler(&out, nil)),
+		RequestLogger: func(r *http.Request, logger *slog.Logger) *slog.Logger {
+			return logger.With("request_id", r.Header.Get("X-Request-ID"))
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if _, err := readLineWithTimeout(bufio.NewReader(resp.Body), time.Second); err != nil {
+		t.Fatalf("failed to read retry line: %v", err)
+	}
+	resp.Body.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(out.String(), "client disconnected") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected disconnect log:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	logs := out.String()
+	for _, want := range []string{`msg="client connected"`, "scope_id=1", "user_id=42", "conn_id=", "request_id=req-1", `reason="client closed"`} {
+		if !strings.Contains(logs, want) {
+			t.Fatalf("expected %q in logs:\n%s", want, logs)
+		}
+	}
+}
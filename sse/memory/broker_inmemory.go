@@ -3,15 +3,36 @@ package memory
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"path"
 	"sync"
+	"sync/atomic"
 
 	"github.com/PabloPavan/eventrail/sse"
 )
 
+type BrokerInMemoryOptions struct {
+	// Logger reports messages dropped because a subscriber was full.
+	// Default discards.
+	Logger *slog.Logger
+	// DropLevel is the level of dropped message records. Default warn.
+	DropLevel slog.Leveler
+	// OnError receives ErrSubscriberFull for every dropped message. Hubs
+	// learn about drops from BrokerMsg.Dropped.
+	OnError func(ctx context.Context, err error)
+}
+
+// ErrSubscriberFull reports a message dropped because a subscription's
+// buffer was full.
+var ErrSubscriberFull = errors.New("subscriber full, message dropped")
+
 type BrokerInMemory struct {
+	opts BrokerInMemoryOptions
+
 	mu   sync.RWMutex
 	subs map[*memSubscription]struct{}
+	seqs map[string]uint64
 }
 
 type memSubscription struct {
@@ -19,10 +40,25 @@ type memSubscription struct {
 	patterns []string
 	ch       chan sse.BrokerMsg
 	once     sync.Once
+	dropped  atomic.Int64
 }
 
 func NewBrokerInMemory() *BrokerInMemory {
-	return &BrokerInMemory{subs: make(map[*memSubscription]struct{})}
+	return NewBrokerInMemoryWithOptions(BrokerInMemoryOptions{})
+}
+
+func NewBrokerInMemoryWithOptions(options BrokerInMemoryOptions) *BrokerInMemory {
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.DiscardHandler)
+	}
+	if options.DropLevel == nil {
+		options.DropLevel = slog.LevelWarn
+	}
+	return &BrokerInMemory{
+		opts: options,
+		subs: make(map[*memSubscription]struct{}),
+		seqs: make(map[string]uint64),
+	}
 }
 
 func (b *BrokerInMemory) Subscribe(ctx context.Context, patterns ...string) (sse.Subscription, error) {
@@ -56,29 +92,53 @@ func (b *BrokerInMemory) Publish(ctx context.Context, channel string, payload []
 		return err
 	}
 
-	msg := sse.BrokerMsg{
-		Channel: channel,
-		Payload: payload,
-	}
-
+	// Sends happen under the read lock so Close cannot close a channel
+	// mid-send; they never block.
 	b.mu.RLock()
-	subs := make([]*memSubscription, 0, len(b.subs))
-	for sub := range b.subs {
-		subs = append(subs, sub)
+	defer b.mu.RUnlock()
+
+	b.send(channel, payload)
+	return nil
+}
+
+// PublishSequenced numbers and sends payload under the write lock, so
+// concurrent publishers deliver in sequence order.
+func (b *BrokerInMemory) PublishSequenced(ctx context.Context, channel string, payload []byte) error {
+	if ctx == nil {
+		return errors.New("context cannot be nil")
 	}
-	b.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seqs[channel]++
+	b.send(channel, sse.SequencePayload(b.seqs[channel], payload))
+	return nil
+}
 
-	for _, sub := range subs {
+// send delivers to matching subscriptions. Callers must hold b.mu.
+func (b *BrokerInMemory) send(channel string, payload []byte) {
+	for sub := range b.subs {
 		if !sub.matches(channel) {
 			continue
 		}
+		// The drop count travels with the subscriber's next message.
+		// Publishers share the read lock, so the count is taken, not read.
+		dropped := sub.dropped.Swap(0)
 		select {
-		case sub.ch <- msg:
+		case sub.ch <- sse.BrokerMsg{Channel: channel, Payload: payload, Dropped: int(dropped)}:
 		default:
+			sub.dropped.Add(dropped + 1)
+			b.opts.Logger.Log(context.Background(), b.opts.DropLevel.Level(), "subscriber full, message dropped",
+				"channel", channel)
+			if b.opts.OnError != nil {
+				b.opts.OnError(context.Background(), fmt.Errorf("%w: %s", ErrSubscriberFull, channel))
+			}
 		}
 	}
-
-	return nil
 }
 
 func (s *memSubscription) Channel() <-chan sse.BrokerMsg {
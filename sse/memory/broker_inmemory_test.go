@@ -2,6 +2,7 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -88,3 +89,59 @@ func TestBrokerInMemoryCloseClosesChannel(t *testing.T) {
 		t.Fatal("timeout waiting for channel close")
 	}
 }
+
+func TestBrokerInMemoryPublishSequenced(t *testing.T) {
+	broker := NewBrokerInMemory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	for _, channel := range []string{"scope:1:students", "scope:1:students", "scope:1:plans"} {
+		if err := broker.PublishSequenced(context.Background(), channel, []byte("x")); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	for _, want := range []string{"\x00s1\nx", "\x00s2\nx", "\x00s1\nx"} {
+		if msg := <-sub.Channel(); string(msg.Payload) != want {
+			t.Fatalf("unexpected payload: %q, want %q", msg.Payload, want)
+		}
+	}
+}
+
+func TestBrokerInMemoryReportsDrops(t *testing.T) {
+	var errs []error
+	broker := NewBrokerInMemoryWithOptions(BrokerInMemoryOptions{
+		OnError: func(_ context.Context, err error) { errs = append(errs, err) },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	for i := 0; i < cap(sub.(*memSubscription).ch)+2; i++ {
+		if err := broker.Publish(context.Background(), "scope:1:students", []byte("x")); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], ErrSubscriberFull) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	for range cap(sub.(*memSubscription).ch) {
+		<-sub.Channel()
+	}
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("x")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if msg := <-sub.Channel(); msg.Dropped != 2 {
+		t.Fatalf("expected 2 dropped with the next message, got %d", msg.Dropped)
+	}
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+)
+
+type Scheduler struct {
+	broker  sse.Broker
+	onError func(ctx context.Context, err error)
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+	closed bool
+}
+
+func NewScheduler(broker sse.Broker, onError func(ctx context.Context, err error)) *Scheduler {
+	return &Scheduler{
+		broker:  broker,
+		onError: onError,
+		timers:  make(map[string]*time.Timer),
+	}
+}
+
+func (s *Scheduler) Schedule(ctx context.Context, at time.Time, channel string, payload []byte) (string, error) {
+	if ctx == nil {
+		return "", errors.New("context cannot be nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	id := sse.NewScheduleID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return "", errors.New("scheduler is closed")
+	}
+	s.timers[id] = time.AfterFunc(time.Until(at), func() {
+		s.mu.Lock()
+		_, pending := s.timers[id]
+		delete(s.timers, id)
+		s.mu.Unlock()
+		if !pending {
+			return
+		}
+
+		pubCtx := context.Background()
+		if err := sse.PublishScheduled(pubCtx, s.broker, channel, payload); err != nil && s.onError != nil {
+			s.onError(pubCtx, err)
+		}
+	})
+	return id, nil
+}
+
+func (s *Scheduler) Cancel(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, exists := s.timers[id]
+	if !exists {
+		return sse.ErrScheduleNotFound
+	}
+	t.Stop()
+	delete(s.timers, id)
+	return nil
+}
+
+// Close cancels every pending event.
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for id, t := range s.timers {
+		t.Stop()
+		delete(s.timers, id)
+	}
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+)
+
+func TestSchedulerPublishesAtTime(t *testing.T) {
+	broker := NewBrokerInMemory()
+	scheduler := NewScheduler(broker, nil)
+	defer scheduler.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	pub := sse.NewPublisherWithOptions(broker, sse.PublisherOptions{Scheduler: scheduler})
+	start := time.Now()
+	if _, err := pub.PublishAfter(context.Background(), 50*time.Millisecond, "scope:1:subscriptions", sse.Event{EventType: "subscription.expiring"}); err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Fatalf("event published too early: %v", elapsed)
+		}
+		if msg.Channel != "scope:1:subscriptions" {
+			t.Fatalf("unexpected channel: %s", msg.Channel)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("timeout waiting for scheduled event")
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	broker := NewBrokerInMemory()
+	scheduler := NewScheduler(broker, nil)
+	defer scheduler.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	id, err := scheduler.Schedule(context.Background(), time.Now().Add(50*time.Millisecond), "scope:1:students", []byte("hello"))
+	if err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+	if err := scheduler.Cancel(context.Background(), id); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if err := scheduler.Cancel(context.Background(), id); !errors.Is(err, sse.ErrScheduleNotFound) {
+		t.Fatalf("expected ErrScheduleNotFound, got %v", err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		t.Fatalf("unexpected message: %v", msg)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
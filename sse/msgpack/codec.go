@@ -0,0 +1,61 @@
+package msgpack
+
+import (
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type envelope struct {
+	EventType string    `msgpack:"event_type"`
+	Data      []byte    `msgpack:"data,omitempty"`
+	ExpiresAt time.Time `msgpack:"expires_at,omitempty"`
+	ID        string    `msgpack:"id,omitempty"`
+	Sequence  uint64    `msgpack:"seq,omitempty"`
+
+	Source          string    `msgpack:"source,omitempty"`
+	Subject         string    `msgpack:"subject,omitempty"`
+	Time            time.Time `msgpack:"time,omitempty"`
+	DataContentType string    `msgpack:"datacontenttype,omitempty"`
+}
+
+type Codec struct{}
+
+func NewCodec() Codec {
+	return Codec{}
+}
+
+func (Codec) Name() string { return "msgpack" }
+
+func (Codec) Marshal(evt sse.Event) ([]byte, error) {
+	return msgpack.Marshal(envelope{
+		EventType: evt.EventType,
+		Data:      evt.Data,
+		ExpiresAt: evt.ExpiresAt,
+		ID:        evt.ID,
+		Sequence:  evt.Sequence,
+
+		Source:          evt.Source,
+		Subject:         evt.Subject,
+		Time:            evt.Time,
+		DataContentType: evt.DataContentType,
+	})
+}
+
+func (Codec) Unmarshal(raw []byte, evt *sse.Event) error {
+	var env envelope
+	if err := msgpack.Unmarshal(raw, &env); err != nil {
+		return err
+	}
+	evt.EventType = env.EventType
+	evt.Data = env.Data
+	evt.ExpiresAt = env.ExpiresAt
+	evt.ID = env.ID
+	evt.Sequence = env.Sequence
+	evt.Source = env.Source
+	evt.Subject = env.Subject
+	evt.Time = env.Time
+	evt.DataContentType = env.DataContentType
+	return nil
+}
@@ -0,0 +1,291 @@
+// Package nats implements sse.Broker on top of NATS core or JetStream.
+//
+// Channels map to subjects by replacing ":" with ".", under SubjectPrefix:
+// gym:1:students is published on eventrail.gym.1.students. Router patterns
+// are translated to subject wildcards (a trailing "*" becomes ">", other
+// segments containing glob characters become "*") and matched locally with
+// the original glob, so channel segments cannot contain ".", whitespace or
+// ">".
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/nats-io/nats.go"
+)
+
+type JetStreamOptions struct {
+	// Stream is the JetStream stream capturing SubjectPrefix.>; it is
+	// created if missing. Default "EVENTRAIL".
+	Stream string
+	// MaxAge bounds how long events are retained for replay. Default 1h.
+	MaxAge time.Duration
+	// Replay delivers events published within this window before the
+	// subscription started. Zero only delivers new events.
+	Replay time.Duration
+}
+
+type BrokerOptions struct {
+	// SubjectPrefix is the first subject token. Default "eventrail".
+	SubjectPrefix string
+	// JetStream enables durable publishing and replay when non-nil.
+	JetStream *JetStreamOptions
+	// Logger reports messages the NATS client dropped because a subscriber
+	// exceeded its pending limits. Default discards.
+	Logger *slog.Logger
+	// DropLevel is the level of dropped message records. Default warn.
+	DropLevel slog.Leveler
+	// OnError receives ErrSubscriberFull for every batch of dropped
+	// messages. Hubs learn about drops from BrokerMsg.Dropped.
+	OnError func(ctx context.Context, err error)
+}
+
+// ErrSubscriberFull reports messages dropped because a subscription fell
+// behind its pending limits.
+var ErrSubscriberFull = errors.New("subscriber full, message dropped")
+
+type Broker struct {
+	conn *nats.Conn
+	opts BrokerOptions
+	js   nats.JetStreamContext
+}
+
+func NewBroker(conn *nats.Conn) (*Broker, error) {
+	return NewBrokerWithOptions(conn, BrokerOptions{})
+}
+
+func NewBrokerWithOptions(conn *nats.Conn, options BrokerOptions) (*Broker, error) {
+	if conn == nil {
+		return nil, errors.New("nats connection cannot be nil")
+	}
+	if options.SubjectPrefix == "" {
+		options.SubjectPrefix = "eventrail"
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.DiscardHandler)
+	}
+	if options.DropLevel == nil {
+		options.DropLevel = slog.LevelWarn
+	}
+
+	b := &Broker{conn: conn, opts: options}
+	if options.JetStream == nil {
+		return b, nil
+	}
+
+	jsOpts := *options.JetStream
+	if jsOpts.Stream == "" {
+		jsOpts.Stream = "EVENTRAIL"
+	}
+	if jsOpts.MaxAge == 0 {
+		jsOpts.MaxAge = time.Hour
+	}
+	b.opts.JetStream = &jsOpts
+
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := js.StreamInfo(jsOpts.Stream); errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     jsOpts.Stream,
+			Subjects: []string{options.SubjectPrefix + ".>"},
+			MaxAge:   jsOpts.MaxAge,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	b.js = js
+	return b, nil
+}
+
+type subscription struct {
+	subs []*nats.Subscription
+	out  chan sse.BrokerMsg
+	done chan struct{}
+
+	once   sync.Once
+	mu     sync.Mutex
+	closed bool
+}
+
+func (b *Broker) Subscribe(ctx context.Context, patterns ...string) (sse.Subscription, error) {
+	if ctx == nil {
+		return nil, errors.New("context cannot be nil")
+	}
+
+	sub := &subscription{
+		out:  make(chan sse.BrokerMsg, 128),
+		done: make(chan struct{}),
+	}
+
+	for _, pattern := range patterns {
+		subject, err := b.patternSubject(pattern)
+		if err != nil {
+			_ = sub.Close()
+			return nil, err
+		}
+
+		handler := sub.handler(b, pattern)
+		var ns *nats.Subscription
+		if b.js != nil {
+			opts := []nats.SubOpt{nats.OrderedConsumer()}
+			if replay := b.opts.JetStream.Replay; replay > 0 {
+				opts = append(opts, nats.StartTime(time.Now().Add(-replay)))
+			} else {
+				opts = append(opts, nats.DeliverNew())
+			}
+			ns, err = b.js.Subscribe(subject, handler, opts...)
+		} else {
+			ns, err = b.conn.Subscribe(subject, handler)
+		}
+		if err != nil {
+			_ = sub.Close()
+			return nil, err
+		}
+		sub.subs = append(sub.subs, ns)
+	}
+
+	if err := b.conn.Flush(); err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = sub.Close()
+		case <-sub.done:
+		}
+	}()
+
+	return sub, nil
+}
+
+func (b *Broker) Publish(ctx context.Context, channel string, payload []byte) error {
+	if ctx == nil {
+		return errors.New("context cannot be nil")
+	}
+	subject, err := b.channelSubject(channel)
+	if err != nil {
+		return err
+	}
+	if b.js != nil {
+		_, err := b.js.Publish(subject, payload, nats.Context(ctx))
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.conn.Publish(subject, payload)
+}
+
+func (s *subscription) handler(b *Broker, pattern string) nats.MsgHandler {
+	// The client calls a subscription's handler from one goroutine, so
+	// these need no lock.
+	var reported, dropped int
+	return func(msg *nats.Msg) {
+		// Sends below block, so a slow hub makes the NATS client drop
+		// messages once the pending limits are reached; the count travels
+		// with the next message.
+		if total, err := msg.Sub.Dropped(); err == nil && total > reported {
+			n := total - reported
+			reported = total
+			dropped += n
+			b.opts.Logger.Log(context.Background(), b.opts.DropLevel.Level(), "subscriber full, messages dropped",
+				"pattern", pattern, "dropped", n)
+			if b.opts.OnError != nil {
+				b.opts.OnError(context.Background(), fmt.Errorf("%w: %d on %s", ErrSubscriberFull, n, pattern))
+			}
+		}
+
+		channel, ok := b.subjectChannel(msg.Subject)
+		if !ok {
+			return
+		}
+		if matched, err := path.Match(pattern, channel); err != nil || !matched {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.closed {
+			return
+		}
+		select {
+		case s.out <- sse.BrokerMsg{Pattern: pattern, Channel: channel, Payload: msg.Data, Dropped: dropped}:
+			dropped = 0
+		case <-s.done:
+		}
+	}
+}
+
+func (s *subscription) Channel() <-chan sse.BrokerMsg { return s.out }
+
+func (s *subscription) Close() error {
+	var errs []error
+	s.once.Do(func() {
+		for _, ns := range s.subs {
+			if err := ns.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+				errs = append(errs, err)
+			}
+		}
+
+		// Handlers hold mu while sending; close done first so a blocked send
+		// returns before we take the lock.
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		close(s.out)
+		s.mu.Unlock()
+	})
+	return errors.Join(errs...)
+}
+
+func (b *Broker) channelSubject(channel string) (string, error) {
+	tokens := strings.Split(channel, ":")
+	for _, t := range tokens {
+		if t == "" || strings.ContainsAny(t, ". \t\r\n>*?[]") {
+			return "", fmt.Errorf("channel %q cannot be mapped to a nats subject", channel)
+		}
+	}
+	return b.opts.SubjectPrefix + "." + strings.Join(tokens, "."), nil
+}
+
+func (b *Broker) patternSubject(pattern string) (string, error) {
+	tokens := strings.Split(pattern, ":")
+	for i, t := range tokens {
+		if t == "" || strings.ContainsAny(t, ". \t\r\n>") {
+			return "", fmt.Errorf("pattern %q cannot be mapped to a nats subject", pattern)
+		}
+		if strings.ContainsAny(t, "*?[]") {
+			// A glob "*" may span ":" separators, so a trailing wildcard
+			// segment must match any number of subject tokens.
+			if i == len(tokens)-1 && strings.Contains(t, "*") {
+				tokens[i] = ">"
+			} else {
+				tokens[i] = "*"
+			}
+		}
+	}
+	return b.opts.SubjectPrefix + "." + strings.Join(tokens, "."), nil
+}
+
+func (b *Broker) subjectChannel(subject string) (string, bool) {
+	rest, ok := strings.CutPrefix(subject, b.opts.SubjectPrefix+".")
+	if !ok {
+		return "", false
+	}
+	return strings.ReplaceAll(rest, ".", ":"), true
+}
@@ -0,0 +1,259 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func runServer(t *testing.T, jetStream bool) *nats.Conn {
+	t.Helper()
+
+	opts := &server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true}
+	if jetStream {
+		opts.JetStream = true
+		opts.StoreDir = t.TempDir()
+	}
+	srv, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatalf("failed to create nats server: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func expectMsg(t *testing.T, sub sse.Subscription) sse.BrokerMsg {
+	t.Helper()
+	select {
+	case msg := <-sub.Channel():
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	return sse.BrokerMsg{}
+}
+
+func TestBrokerPublishSubscribe(t *testing.T) {
+	broker, err := NewBroker(runServer(t, false))
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	if err := broker.Publish(context.Background(), "scope:2:students", []byte("other")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("hello")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	msg := expectMsg(t, sub)
+	if msg.Channel != "scope:1:students" || string(msg.Payload) != "hello" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg.Pattern != "scope:1:*" {
+		t.Fatalf("unexpected pattern: %s", msg.Pattern)
+	}
+}
+
+func TestBrokerTrailingWildcardSpansSegments(t *testing.T) {
+	broker, err := NewBroker(runServer(t, false))
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("hello")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if msg := expectMsg(t, sub); msg.Channel != "scope:1:students" {
+		t.Fatalf("unexpected channel: %s", msg.Channel)
+	}
+}
+
+func TestBrokerRejectsUnmappableChannel(t *testing.T) {
+	broker, err := NewBroker(runServer(t, false))
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	if err := broker.Publish(context.Background(), "scope:1:a.b", []byte("x")); err == nil {
+		t.Fatal("expected error for channel containing '.'")
+	}
+	if _, err := broker.Subscribe(context.Background(), "scope:1:a b"); err == nil {
+		t.Fatal("expected error for pattern containing whitespace")
+	}
+}
+
+func TestBrokerCloseClosesChannel(t *testing.T) {
+	broker, err := NewBroker(runServer(t, false))
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-sub.Channel():
+		if ok {
+			t.Fatal("expected channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for channel close")
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatalf("second close failed: %v", err)
+	}
+}
+
+func TestBrokerJetStreamReplay(t *testing.T) {
+	nc := runServer(t, true)
+	broker, err := NewBrokerWithOptions(nc, BrokerOptions{
+		JetStream: &JetStreamOptions{Replay: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("before")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	if msg := expectMsg(t, sub); string(msg.Payload) != "before" {
+		t.Fatalf("expected replayed message, got %q", msg.Payload)
+	}
+
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("after")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if msg := expectMsg(t, sub); string(msg.Payload) != "after" {
+		t.Fatalf("unexpected payload: %q", msg.Payload)
+	}
+}
+
+func TestBrokerJetStreamDeliverNew(t *testing.T) {
+	nc := runServer(t, true)
+	broker, err := NewBrokerWithOptions(nc, BrokerOptions{JetStream: &JetStreamOptions{}})
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("before")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("after")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if msg := expectMsg(t, sub); string(msg.Payload) != "after" {
+		t.Fatalf("expected only new messages, got %q", msg.Payload)
+	}
+}
+
+func TestBrokerReportsClientDrops(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	broker, err := NewBrokerWithOptions(runServer(t, false), BrokerOptions{
+		OnError: func(_ context.Context, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	if err := sub.(*subscription).subs[0].SetPendingLimits(1, -1); err != nil {
+		t.Fatalf("set pending limits failed: %v", err)
+	}
+
+	const published = 500
+	for i := 0; i < published; i++ {
+		if err := broker.Publish(context.Background(), "scope:1:students", []byte("x")); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	if err := broker.conn.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	var received, dropped int
+	for received+dropped < published {
+		msg := expectMsg(t, sub)
+		received++
+		dropped += msg.Dropped
+	}
+	if dropped == 0 || received+dropped != published {
+		t.Fatalf("expected drops to be accounted for, got %d received and %d dropped", received, dropped)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) == 0 || !errors.Is(errs[0], ErrSubscriberFull) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
@@ -2,6 +2,7 @@ package sse
 
 import (
 	"context"
+	"log/slog"
 	"time"
 )
 
@@ -10,8 +11,16 @@ type BackpressurePolicy int
 const (
 	BackpressureDrop BackpressurePolicy = iota
 	BackpressureDisconnect
+	BackpressureDropOldest
+	BackpressureCoalesce
 )
 
+// GapEventType is the SSE event sent to a client before the next delivered
+// event when BackpressureDropOldest or BackpressureCoalesce discarded queued
+// messages, or when the hub saw a hole in a channel's sequence numbers. It is
+// subject to EventNamePrefix and carries {"dropped":n}.
+const GapEventType = "stream.gap"
+
 type ChannelRouter func(p *Principal) []string
 
 type EventEncoder func(raw []byte) (eventtype string, data []byte, err error)
@@ -20,10 +29,20 @@ type Hooks struct {
 	OnClientConnect    func(scopeID int64)
 	OnClientDisconnect func(scopeID int64)
 	OnClientDropped    func(scopeID int64, reason string)
+	OnConnect          func(info ConnInfo)
+	OnDisconnect       func(info ConnInfo, reason DisconnectReason)
 	OnEventBroadcast   func(scopeID int64, clients int)
 	OnHubStarted       func(scopeID int64, patterns []string)
 	OnHubStopped       func(scopeID int64)
+	OnSlowConsumer     func(scopeID int64, stats SlowConsumerStats)
+	OnEventExpired     func(scopeID int64)
+	OnDuplicateEvent   func(scopeID int64)
+	OnSequenceGap      func(scopeID int64, channel string, missed uint64)
 	OnError            func(ctx context.Context, err error)
+	// OnConnDropped and OnConnSlow fire alongside OnClientDropped and
+	// OnSlowConsumer with the affected connection.
+	OnConnDropped func(info ConnInfo, reason string)
+	OnConnSlow    func(info ConnInfo, stats SlowConsumerStats)
 }
 
 type Options struct {
@@ -31,27 +50,81 @@ type Options struct {
 	Router   ChannelRouter
 	Context  context.Context
 
+	PublishAuthorizer PublishAuthorizer
+	ChannelValidation ChannelValidationMode
+
 	EventNamePrefix string
 
 	HeartbeatInterval time.Duration
 	RetryMilliseconds int
 	Headers           map[string]string
+	CORS              CORSOptions
+	// AllowedOrigins rejects requests whose Origin header matches none of
+	// these entries with 403 before the principal is resolved, whether or
+	// not CORS is enabled. Entries use the CORSOptions.AllowedOrigins syntax;
+	// requests without an Origin header are allowed.
+	AllowedOrigins []string
+	// GzipStream gzip-encodes the stream for clients that accept it,
+	// flushing the compressor after every write.
+	GzipStream bool
 
 	ClientBufferSize int
 	Backpressure     BackpressurePolicy
 	HubIdleTimeout   time.Duration
+	// ResubscribeInterval is the delay between attempts to replace a broker
+	// subscription that closed while the hub still had clients. Messages
+	// published meanwhile are lost; a Sequencer reports them as a gap.
+	ResubscribeInterval time.Duration
+
+	WriteTimeout time.Duration
+	SlowConsumer SlowConsumerPolicy
+	// MaxConnectionLifetime closes streams after this long so clients
+	// reconnect, e.g. to spread load after a deploy. Zero means no limit.
+	MaxConnectionLifetime time.Duration
+
+	// DedupWindow is the number of recent event IDs each hub remembers to
+	// drop repeats before fan-out. Negative disables duplicate suppression.
+	DedupWindow  int
+	Deduplicator Deduplicator
+	Sequencer    Sequencer
 
 	EventEncoder EventEncoder
+	Codec        Codec
+	Codecs       []Codec
+	Scheduler    Scheduler
+	Compression  Compression
+	// Keyring signs (and optionally encrypts) published payloads; hubs drop
+	// payloads that fail verification and report them through OnError.
+	Keyring *Keyring
 
 	Hooks Hooks
+
+	// Logger receives structured logs from the handler, hubs and hub
+	// manager. Default discards everything. Brokers are not given it; every
+	// broker package takes its own Logger option.
+	Logger        *slog.Logger
+	LogLevels     LogLevels
+	RequestLogger RequestLogger
 }
 
 func applyDefaultOptions(opts *Options) {
 	if opts.Context == nil {
 		opts.Context = context.Background()
 	}
+	if opts.Codec == nil {
+		opts.Codec = JSONCodec{}
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.New(slog.DiscardHandler)
+	}
+	if opts.LogLevels.Drop == nil {
+		opts.LogLevels.Drop = slog.LevelDebug
+	}
+	if opts.LogLevels.Connect == nil {
+		opts.LogLevels.Connect = slog.LevelInfo
+	}
 	if opts.EventEncoder == nil {
-		opts.EventEncoder = defaultEventEncoder
+		opts.EventEncoder = codecEventEncoder(opts.Codec)
 	}
 	opts.EventEncoder = applyEventNamePrefix(opts.EventEncoder, opts.EventNamePrefix)
 	if opts.HeartbeatInterval == 0 {
@@ -66,10 +139,24 @@ func applyDefaultOptions(opts *Options) {
 	if opts.HubIdleTimeout == 0 {
 		opts.HubIdleTimeout = 5 * time.Minute
 	}
+	if opts.ResubscribeInterval == 0 {
+		opts.ResubscribeInterval = time.Second
+	}
+	if opts.DedupWindow == 0 {
+		opts.DedupWindow = 1024
+	}
+	if opts.SlowConsumer.LatencyWindow == 0 {
+		opts.SlowConsumer.LatencyWindow = 20
+	}
+	if opts.CORS.enabled() && opts.CORS.AllowedHeaders == nil {
+		opts.CORS.AllowedHeaders = []string{"Last-Event-ID", CodecHeader}
+	}
 	if opts.Headers == nil {
 		opts.Headers = make(map[string]string)
 	}
-	if opts.Backpressure != BackpressureDrop && opts.Backpressure != BackpressureDisconnect {
+	switch opts.Backpressure {
+	case BackpressureDrop, BackpressureDisconnect, BackpressureDropOldest, BackpressureCoalesce:
+	default:
 		opts.Backpressure = BackpressureDisconnect
 	}
 }
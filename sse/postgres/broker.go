@@ -0,0 +1,355 @@
+// Package postgres implements sse.Broker on top of PostgreSQL LISTEN/NOTIFY.
+//
+// Every event is sent on one NOTIFY channel and each process holds a single
+// LISTEN connection; router patterns are matched locally. Payloads that
+// would exceed the 8000-byte NOTIFY limit are stored in a table and the
+// notification only carries the row id.
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxNotifyPayload is PostgreSQL's default NOTIFY payload limit.
+const maxNotifyPayload = 8000
+
+const (
+	kindInline  = 'i'
+	kindSpilled = 's'
+)
+
+type BrokerOptions struct {
+	// Context bounds the listener goroutine. Default context.Background().
+	Context context.Context
+	// NotifyChannel is the LISTEN/NOTIFY channel shared by all events.
+	// Default "eventrail".
+	NotifyChannel string
+	// SpillTable stores payloads too large for NOTIFY. It is created if
+	// missing. Default "eventrail_payloads".
+	SpillTable string
+	// SpillRetention is how long spilled payloads are kept for listeners to
+	// fetch. Default 5m.
+	SpillRetention time.Duration
+	// ReconnectInterval is the delay before re-establishing a lost LISTEN
+	// connection. Default 1s.
+	ReconnectInterval time.Duration
+	// Logger reports listener reconnects and dropped notifications. Default
+	// discards.
+	Logger *slog.Logger
+	// DropLevel is the level of records for messages dropped because a
+	// subscriber was full. Default warn.
+	DropLevel slog.Leveler
+	// OnError receives listener errors and ErrSubscriberFull for every
+	// dropped message. Hubs learn about drops from BrokerMsg.Dropped.
+	OnError func(ctx context.Context, err error)
+}
+
+// ErrSubscriberFull reports a message dropped because a subscription's
+// buffer was full.
+var ErrSubscriberFull = errors.New("subscriber full, message dropped")
+
+type Broker struct {
+	pool *pgxpool.Pool
+	opts BrokerOptions
+
+	spillInsert string
+	spillSelect string
+
+	mu   sync.RWMutex
+	subs map[*subscription]struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewBroker(pool *pgxpool.Pool) (*Broker, error) {
+	return NewBrokerWithOptions(pool, BrokerOptions{})
+}
+
+func NewBrokerWithOptions(pool *pgxpool.Pool, options BrokerOptions) (*Broker, error) {
+	if pool == nil {
+		return nil, errors.New("postgres pool cannot be nil")
+	}
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+	if options.NotifyChannel == "" {
+		options.NotifyChannel = "eventrail"
+	}
+	if options.SpillTable == "" {
+		options.SpillTable = "eventrail_payloads"
+	}
+	if options.SpillRetention == 0 {
+		options.SpillRetention = 5 * time.Minute
+	}
+	if options.ReconnectInterval == 0 {
+		options.ReconnectInterval = time.Second
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.DiscardHandler)
+	}
+	if options.DropLevel == nil {
+		options.DropLevel = slog.LevelWarn
+	}
+
+	table := pgx.Identifier{options.SpillTable}.Sanitize()
+	_, err := pool.Exec(options.Context, `CREATE TABLE IF NOT EXISTS `+table+` (
+	id bigserial PRIMARY KEY,
+	payload bytea NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now()
+)`)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(options.Context)
+	b := &Broker{
+		pool: pool,
+		opts: options,
+		// Old rows are pruned by the same statement that spills a new one,
+		// and the notification is only delivered once the row is committed.
+		spillInsert: `WITH pruned AS (
+	DELETE FROM ` + table + ` WHERE created_at < now() - make_interval(secs => $3)
+), inserted AS (
+	INSERT INTO ` + table + ` (payload) VALUES ($2) RETURNING id
+)
+SELECT pg_notify($1, $4 || id::text) FROM inserted`,
+		spillSelect: `SELECT payload FROM ` + table + ` WHERE id = $1`,
+		subs:        make(map[*subscription]struct{}),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	go b.listen(ctx)
+	return b, nil
+}
+
+type subscription struct {
+	broker   *Broker
+	patterns []string
+	out      chan sse.BrokerMsg
+	closed   bool // guarded by broker.mu
+	dropped  atomic.Int64
+}
+
+func (b *Broker) Subscribe(ctx context.Context, patterns ...string) (sse.Subscription, error) {
+	if ctx == nil {
+		return nil, errors.New("context cannot be nil")
+	}
+
+	sub := &subscription{
+		broker:   b,
+		patterns: append([]string(nil), patterns...),
+		out:      make(chan sse.BrokerMsg, 128),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		_ = sub.Close()
+	}()
+
+	return sub, nil
+}
+
+func (b *Broker) Publish(ctx context.Context, channel string, payload []byte) error {
+	if ctx == nil {
+		return errors.New("context cannot be nil")
+	}
+	if strings.ContainsRune(channel, '\n') {
+		return fmt.Errorf("channel %q cannot contain newlines", channel)
+	}
+
+	if msg := encodeInline(channel, payload); len(msg) <= maxNotifyPayload {
+		_, err := b.pool.Exec(ctx, `SELECT pg_notify($1, $2)`, b.opts.NotifyChannel, msg)
+		return err
+	}
+
+	prefix := string(kindSpilled) + channel + "\n"
+	if len(prefix)+20 > maxNotifyPayload {
+		return fmt.Errorf("channel %q is too long for a notification", channel)
+	}
+	_, err := b.pool.Exec(ctx, b.spillInsert,
+		b.opts.NotifyChannel, payload, b.opts.SpillRetention.Seconds(), prefix)
+	return err
+}
+
+// Close stops the listener. Open subscriptions stop receiving messages.
+func (b *Broker) Close() error {
+	b.cancel()
+	<-b.done
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		if !sub.closed {
+			sub.closed = true
+			close(sub.out)
+		}
+	}
+	b.subs = make(map[*subscription]struct{})
+	return nil
+}
+
+func (b *Broker) listen(ctx context.Context) {
+	defer close(b.done)
+
+	for {
+		err := b.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		b.opts.Logger.WarnContext(ctx, "listener connection lost, reconnecting",
+			"error", err, "retry_in", b.opts.ReconnectInterval)
+		b.reportError(ctx, err)
+
+		t := time.NewTimer(b.opts.ReconnectInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// listenOnce holds a dedicated connection LISTENing on NotifyChannel until it
+// fails. Notifications sent while disconnected are lost.
+func (b *Broker) listenOnce(ctx context.Context) error {
+	pc, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn := pc.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.opts.NotifyChannel}.Sanitize()); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		channel, payload, err := b.decode(ctx, n.Payload)
+		if err != nil {
+			b.opts.Logger.WarnContext(ctx, "notification dropped", "error", err)
+			b.reportError(ctx, err)
+			continue
+		}
+		b.dispatch(channel, payload)
+	}
+}
+
+func (b *Broker) decode(ctx context.Context, msg string) (string, []byte, error) {
+	kind, channel, data, err := splitNotification(msg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch kind {
+	case kindInline:
+		payload, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", nil, err
+		}
+		return channel, payload, nil
+	default:
+		id, err := strconv.ParseInt(data, 10, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid spilled payload id %q", data)
+		}
+		var payload []byte
+		if err := b.pool.QueryRow(ctx, b.spillSelect, id).Scan(&payload); err != nil {
+			return "", nil, fmt.Errorf("fetch spilled payload %d: %w", id, err)
+		}
+		return channel, payload, nil
+	}
+}
+
+func (b *Broker) dispatch(channel string, payload []byte) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		pattern, ok := sub.match(channel)
+		if !ok {
+			continue
+		}
+		// A full subscriber must not stall every other hub on the
+		// connection; the drop count travels with its next message.
+		dropped := sub.dropped.Load()
+		select {
+		case sub.out <- sse.BrokerMsg{Pattern: pattern, Channel: channel, Payload: payload, Dropped: int(dropped)}:
+			sub.dropped.Add(-dropped)
+		default:
+			sub.dropped.Add(1)
+			b.opts.Logger.Log(context.Background(), b.opts.DropLevel.Level(), "subscriber full, message dropped",
+				"channel", channel, "pattern", pattern)
+			b.reportError(context.Background(), fmt.Errorf("%w: %s", ErrSubscriberFull, channel))
+		}
+	}
+}
+
+func (b *Broker) reportError(ctx context.Context, err error) {
+	if err != nil && b.opts.OnError != nil {
+		b.opts.OnError(ctx, err)
+	}
+}
+
+func (s *subscription) Channel() <-chan sse.BrokerMsg { return s.out }
+
+func (s *subscription) Close() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		delete(s.broker.subs, s)
+		close(s.out)
+	}
+	return nil
+}
+
+func (s *subscription) match(channel string) (string, bool) {
+	for _, pattern := range s.patterns {
+		if ok, err := path.Match(pattern, channel); err == nil && ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// encodeInline formats a notification carrying the payload itself. NOTIFY
+// payloads must be valid text, so binary codecs are base64-encoded.
+func encodeInline(channel string, payload []byte) string {
+	return string(kindInline) + channel + "\n" + base64.StdEncoding.EncodeToString(payload)
+}
+
+func splitNotification(msg string) (kind byte, channel, data string, err error) {
+	if msg == "" || (msg[0] != kindInline && msg[0] != kindSpilled) {
+		return 0, "", "", errors.New("malformed notification")
+	}
+	channel, data, ok := strings.Cut(msg[1:], "\n")
+	if !ok {
+		return 0, "", "", errors.New("malformed notification")
+	}
+	return msg[0], channel, data, nil
+}
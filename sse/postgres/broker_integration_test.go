@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestBrokerIntegrationPostgres(t *testing.T) {
+	dsn := os.Getenv("INTEGRATION_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("INTEGRATION_POSTGRES_DSN not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+	if err := waitForPostgres(context.Background(), pool, 5*time.Second); err != nil {
+		t.Fatalf("postgres not ready: %v", err)
+	}
+
+	broker, err := NewBroker(pool)
+	if err != nil {
+		t.Fatalf("new broker failed: %v", err)
+	}
+	defer broker.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	large := []byte(strings.Repeat("x", 3*maxNotifyPayload))
+	for _, payload := range [][]byte{[]byte("hello"), large} {
+		if err := broker.Publish(context.Background(), "scope:1:students", payload); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+
+		select {
+		case msg := <-sub.Channel():
+			if msg.Channel != "scope:1:students" {
+				t.Fatalf("unexpected channel: %s", msg.Channel)
+			}
+			if string(msg.Payload) != string(payload) {
+				t.Fatalf("unexpected payload of %d bytes", len(msg.Payload))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+
+	_ = sub.Close()
+}
+
+func waitForPostgres(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := pool.Ping(ctx); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return context.DeadlineExceeded
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
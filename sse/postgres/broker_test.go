@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewBrokerRequiresPool(t *testing.T) {
+	if _, err := NewBroker(nil); err == nil {
+		t.Fatal("expected error for nil pool")
+	}
+}
+
+func TestInlineNotificationRoundTrip(t *testing.T) {
+	payload := []byte{0x00, 0xff, '\n', 'a'}
+	msg := encodeInline("scope:1:students", payload)
+
+	b := &Broker{}
+	channel, got, err := b.decode(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if channel != "scope:1:students" {
+		t.Fatalf("unexpected channel: %s", channel)
+	}
+	if string(got) != string(payload) {
+		t.Fatalf("unexpected payload: %v", got)
+	}
+}
+
+func TestDecodeRejectsMalformedNotification(t *testing.T) {
+	b := &Broker{}
+	for _, msg := range []string{"", "xscope:1:a\nAA==", "iscope:1:a", "sscope:1:a\nnot-a-number"} {
+		if _, _, err := b.decode(context.Background(), msg); err == nil {
+			t.Fatalf("expected error for %q", msg)
+		}
+	}
+}
+
+func TestPublishRejectsNewlineInChannel(t *testing.T) {
+	b := &Broker{}
+	if err := b.Publish(context.Background(), "scope:1:a\nb", []byte("x")); err == nil {
+		t.Fatal("expected error for channel containing newline")
+	}
+}
+
+func TestLargePayloadExceedsInlineLimit(t *testing.T) {
+	msg := encodeInline("scope:1:students", []byte(strings.Repeat("x", 6000)))
+	if len(msg) <= maxNotifyPayload {
+		t.Fatalf("expected base64 payload to exceed the notify limit, got %d bytes", len(msg))
+	}
+}
+
+func TestDispatchMatchesPatternsLocally(t *testing.T) {
+	b := &Broker{subs: make(map[*subscription]struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := b.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	b.dispatch("scope:2:students", []byte("other"))
+	b.dispatch("scope:1:students", []byte("hello"))
+
+	select {
+	case msg := <-sub.Channel():
+		if msg.Channel != "scope:1:students" || msg.Pattern != "scope:1:*" || string(msg.Payload) != "hello" {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout waiting for message")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-sub.Channel():
+		if ok {
+			t.Fatal("expected channel to be closed")
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout waiting for channel close")
+	}
+}
+
+func TestDispatchReportsDrops(t *testing.T) {
+	var reported int
+	b := &Broker{
+		opts: BrokerOptions{
+			Logger:    slog.New(slog.DiscardHandler),
+			DropLevel: slog.LevelWarn,
+			OnError: func(_ context.Context, err error) {
+				if errors.Is(err, ErrSubscriberFull) {
+					reported++
+				}
+			},
+		},
+		subs: make(map[*subscription]struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := b.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	buffered := cap(sub.(*subscription).out)
+	for i := 0; i < buffered+2; i++ {
+		b.dispatch("scope:1:students", []byte("x"))
+	}
+	if reported != 2 {
+		t.Fatalf("expected 2 reported drops, got %d", reported)
+	}
+
+	for i := 0; i < buffered; i++ {
+		<-sub.Channel()
+	}
+	b.dispatch("scope:1:students", []byte("y"))
+	if msg := <-sub.Channel(); msg.Dropped != 2 || string(msg.Payload) != "y" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestCloseRacesSubscriptionClose(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		b := &Broker{subs: make(map[*subscription]struct{}), cancel: cancel, done: make(chan struct{})}
+		close(b.done)
+		sub, err := b.Subscribe(ctx, "scope:1:*")
+		if err != nil {
+			t.Fatalf("subscribe failed: %v", err)
+		}
+
+		closed := make(chan struct{})
+		go func() {
+			_ = sub.Close()
+			close(closed)
+		}()
+		_ = b.Close()
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("deadlock between Broker.Close and subscription Close")
+		}
+	}
+}
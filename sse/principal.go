@@ -1,10 +1,16 @@
 package sse
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type Principal struct {
 	UserID  int64
 	ScopeID int64
+	// ExpiresAt, when set, is when the credentials behind the principal
+	// expire; the stream is closed then so the client re-authenticates.
+	ExpiresAt time.Time
 }
 
 type PrincipalResolver interface {
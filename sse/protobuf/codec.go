@@ -0,0 +1,174 @@
+// Package protobuf encodes events as the protobuf message
+//
+//	message Event {
+//	  string event_type = 1;
+//	  bytes data = 2;
+//	  int64 expires_at_unix_ms = 3;
+//	  string id = 4;
+//	  uint64 seq = 5;
+//	  string source = 6;
+//	  string subject = 7;
+//	  int64 time_unix_ms = 8;
+//	  string datacontenttype = 9;
+//	}
+//
+// so services can produce and consume envelopes with their own generated code.
+package protobuf
+
+import (
+	"errors"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	fieldEventType protowire.Number = 1
+	fieldData      protowire.Number = 2
+	fieldExpiresAt protowire.Number = 3
+	fieldID        protowire.Number = 4
+	fieldSequence  protowire.Number = 5
+
+	fieldSource          protowire.Number = 6
+	fieldSubject         protowire.Number = 7
+	fieldTime            protowire.Number = 8
+	fieldDataContentType protowire.Number = 9
+)
+
+type Codec struct{}
+
+func NewCodec() Codec {
+	return Codec{}
+}
+
+func (Codec) Name() string { return "protobuf" }
+
+func (Codec) Marshal(evt sse.Event) ([]byte, error) {
+	var b []byte
+	if evt.EventType != "" {
+		b = protowire.AppendTag(b, fieldEventType, protowire.BytesType)
+		b = protowire.AppendString(b, evt.EventType)
+	}
+	if len(evt.Data) > 0 {
+		b = protowire.AppendTag(b, fieldData, protowire.BytesType)
+		b = protowire.AppendBytes(b, evt.Data)
+	}
+	if !evt.ExpiresAt.IsZero() {
+		b = protowire.AppendTag(b, fieldExpiresAt, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(evt.ExpiresAt.UnixMilli()))
+	}
+	if evt.ID != "" {
+		b = protowire.AppendTag(b, fieldID, protowire.BytesType)
+		b = protowire.AppendString(b, evt.ID)
+	}
+	if evt.Sequence != 0 {
+		b = protowire.AppendTag(b, fieldSequence, protowire.VarintType)
+		b = protowire.AppendVarint(b, evt.Sequence)
+	}
+	if evt.Source != "" {
+		b = protowire.AppendTag(b, fieldSource, protowire.BytesType)
+		b = protowire.AppendString(b, evt.Source)
+	}
+	if evt.Subject != "" {
+		b = protowire.AppendTag(b, fieldSubject, protowire.BytesType)
+		b = protowire.AppendString(b, evt.Subject)
+	}
+	if !evt.Time.IsZero() {
+		b = protowire.AppendTag(b, fieldTime, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(evt.Time.UnixMilli()))
+	}
+	if evt.DataContentType != "" {
+		b = protowire.AppendTag(b, fieldDataContentType, protowire.BytesType)
+		b = protowire.AppendString(b, evt.DataContentType)
+	}
+	return b, nil
+}
+
+func (Codec) Unmarshal(raw []byte, evt *sse.Event) error {
+	var out sse.Event
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		raw = raw[n:]
+
+		switch {
+		case num == fieldEventType && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.EventType = v
+			raw = raw[n:]
+		case num == fieldData && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.Data = append([]byte(nil), v...)
+			raw = raw[n:]
+		case num == fieldExpiresAt && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.ExpiresAt = time.UnixMilli(int64(v))
+			raw = raw[n:]
+		case num == fieldID && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.ID = v
+			raw = raw[n:]
+		case num == fieldSequence && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.Sequence = v
+			raw = raw[n:]
+		case num == fieldSource && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.Source = v
+			raw = raw[n:]
+		case num == fieldSubject && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.Subject = v
+			raw = raw[n:]
+		case num == fieldDataContentType && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.DataContentType = v
+			raw = raw[n:]
+		case num == fieldTime && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			out.Time = time.UnixMilli(int64(v))
+			raw = raw[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			raw = raw[n:]
+		}
+	}
+	if out.EventType == "" && len(out.Data) == 0 {
+		return errors.New("empty protobuf event")
+	}
+	*evt = out
+	return nil
+}
@@ -0,0 +1,66 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec()
+	expiresAt := time.UnixMilli(time.Now().Add(time.Minute).UnixMilli())
+
+	raw, err := codec.Marshal(sse.Event{EventType: "students.changed", Data: []byte(`{"id":1}`), ExpiresAt: expiresAt, ID: "evt-1", Sequence: 7,
+		Source: "/gyms/1", Subject: "students/1", Time: expiresAt, DataContentType: "application/json"})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var evt sse.Event
+	if err := codec.Unmarshal(raw, &evt); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if evt.EventType != "students.changed" {
+		t.Fatalf("unexpected event type: %s", evt.EventType)
+	}
+	if string(evt.Data) != `{"id":1}` {
+		t.Fatalf("unexpected data: %s", string(evt.Data))
+	}
+	if !evt.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected expiry: %v", evt.ExpiresAt)
+	}
+	if evt.ID != "evt-1" {
+		t.Fatalf("unexpected id: %s", evt.ID)
+	}
+	if evt.Sequence != 7 {
+		t.Fatalf("unexpected sequence: %d", evt.Sequence)
+	}
+	if evt.Source != "/gyms/1" || evt.Subject != "students/1" || !evt.Time.Equal(expiresAt) || evt.DataContentType != "application/json" {
+		t.Fatalf("unexpected CloudEvents attributes: %+v", evt)
+	}
+}
+
+func TestCodecRoundTripWithoutExpiry(t *testing.T) {
+	codec := NewCodec()
+
+	raw, err := codec.Marshal(sse.Event{EventType: "students.changed"})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var evt sse.Event
+	if err := codec.Unmarshal(raw, &evt); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if !evt.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected expiry: %v", evt.ExpiresAt)
+	}
+}
+
+func TestCodecUnmarshalInvalid(t *testing.T) {
+	var evt sse.Event
+	if err := NewCodec().Unmarshal([]byte("{"), &evt); err == nil {
+		t.Fatal("expected error for invalid payload")
+	}
+}
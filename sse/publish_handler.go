@@ -0,0 +1,122 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const maxPublishBodyBytes = 1 << 20
+
+type PublishAuthorizer interface {
+	AuthorizePublish(r *http.Request, channel string) error
+}
+
+type PublishRequest struct {
+	Channel   string          `json:"channel"`
+	EventType string          `json:"event_type"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+type PublishResult struct {
+	Index   int    `json:"index"`
+	Channel string `json:"channel"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+type publishResponse struct {
+	Results []PublishResult `json:"results"`
+}
+
+func newPublishHandler(publisher *Publisher, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if opts.PublishAuthorizer == nil {
+			http.Error(w, "publishing is not enabled", http.StatusForbidden)
+			return
+		}
+
+		reqs, err := decodePublishRequests(http.MaxBytesReader(w, r.Body, maxPublishBodyBytes))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		results := make([]PublishResult, len(reqs))
+		for i, req := range reqs {
+			results[i] = PublishResult{Index: i, Channel: req.Channel}
+
+			if err := ValidateChannel(req.Channel); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			if err := opts.PublishAuthorizer.AuthorizePublish(r, req.Channel); err != nil {
+				results[i].Error = fmt.Sprintf("forbidden: %v", err)
+				continue
+			}
+			if err := publisher.PublishEvent(r.Context(), req.Channel, Event{
+				EventType: req.EventType,
+				Data:      req.Data,
+			}); err != nil {
+				results[i].Error = err.Error()
+				if opts.Hooks.OnError != nil {
+					opts.Hooks.OnError(r.Context(), fmt.Errorf("failed to publish to %s: %w", req.Channel, err))
+				}
+				continue
+			}
+			results[i].OK = true
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(publishResponse{Results: results})
+	})
+}
+
+// decodePublishRequests accepts a single event object, an array of events,
+// or {"events": [...]}.
+func decodePublishRequests(body io.Reader) ([]PublishRequest, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimSpace(raw)
+
+	var reqs []PublishRequest
+	switch {
+	case len(raw) > 0 && raw[0] == '[':
+		if err := json.Unmarshal(raw, &reqs); err != nil {
+			return nil, err
+		}
+	case len(raw) > 0 && raw[0] == '{':
+		var batch struct {
+			Events []PublishRequest `json:"events"`
+		}
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return nil, err
+		}
+		if batch.Events != nil {
+			reqs = batch.Events
+			break
+		}
+		var req PublishRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, err
+		}
+		reqs = []PublishRequest{req}
+	default:
+		return nil, errors.New("expected an event object or array")
+	}
+
+	if len(reqs) == 0 {
+		return nil, errors.New("no events")
+	}
+	return reqs, nil
+}
@@ -0,0 +1,131 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type authorizerFunc func(*http.Request, string) error
+
+func (f authorizerFunc) AuthorizePublish(r *http.Request, channel string) error {
+	return f(r, channel)
+}
+
+func newPublishTestServer(t *testing.T, broker Broker, authorizer PublishAuthorizer) *Server {
+	t.Helper()
+
+	server, err := NewServer(broker, Options{
+		Resolver: resolverFunc(func(*http.Request) (*Principal, error) {
+			return &Principal{UserID: 1, ScopeID: 1}, nil
+		}),
+		Router:            func(*Principal) []string { return []string{"scope:1:*"} },
+		PublishAuthorizer: authorizer,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return server
+}
+
+func TestPublishHandlerBatchResults(t *testing.T) {
+	broker := newTestBroker()
+	server := newPublishTestServer(t, broker, authorizerFunc(func(_ *http.Request, channel string) error {
+		if strings.HasPrefix(channel, "scope:2:") {
+			return errors.New("scope 2 not allowed")
+		}
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	body := `[
+		{"channel":"scope:1:students","event_type":"students.changed","data":{"id":1}},
+		{"channel":"scope:2:students","event_type":"students.changed"},
+		{"channel":"global","event_type":"students.changed"}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/publish", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.PublishHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	var resp publishResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+	if !resp.Results[0].OK || resp.Results[1].OK || resp.Results[2].OK {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		if msg.Channel != "scope:1:students" {
+			t.Fatalf("unexpected channel: %s", msg.Channel)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout waiting for event")
+	}
+}
+
+func TestPublishHandlerSingleEvent(t *testing.T) {
+	server := newPublishTestServer(t, newTestBroker(), authorizerFunc(func(*http.Request, string) error { return nil }))
+
+	body := `{"channel":"scope:1:students","event_type":"students.changed"}`
+	req := httptest.NewRequest(http.MethodPost, "/publish", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.PublishHandler().ServeHTTP(rec, req)
+
+	var resp publishResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(resp.Results) != 1 || !resp.Results[0].OK {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+}
+
+func TestPublishHandlerRejectsRequests(t *testing.T) {
+	allowAll := authorizerFunc(func(*http.Request, string) error { return nil })
+
+	tests := []struct {
+		name       string
+		authorizer PublishAuthorizer
+		method     string
+		body       string
+		status     int
+	}{
+		{"no authorizer", nil, http.MethodPost, `{}`, http.StatusForbidden},
+		{"wrong method", allowAll, http.MethodGet, ``, http.StatusMethodNotAllowed},
+		{"invalid json", allowAll, http.MethodPost, `{`, http.StatusBadRequest},
+		{"empty batch", allowAll, http.MethodPost, `[]`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newPublishTestServer(t, newTestBroker(), tt.authorizer)
+			req := httptest.NewRequest(tt.method, "/publish", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			server.PublishHandler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("unexpected status: %d", rec.Code)
+			}
+		})
+	}
+}
@@ -2,34 +2,119 @@ package sse
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"time"
 )
 
+type PublisherOptions struct {
+	Codec        Codec
+	Scheduler    Scheduler
+	Deduplicator Deduplicator
+	Sequencer    Sequencer
+	// Compression compresses large payloads before they reach the broker.
+	// Hubs decompress them regardless of their own settings.
+	Compression Compression
+	Keyring     *Keyring
+
+	ChannelValidation ChannelValidationMode
+	OnError           func(ctx context.Context, err error)
+}
+
 type Publisher struct {
 	broker Broker
+	opts   PublisherOptions
 }
 
 func NewPublisher(broker Broker) *Publisher {
-	return &Publisher{broker: broker}
+	return NewPublisherWithOptions(broker, PublisherOptions{})
+}
+
+func NewPublisherWithOptions(broker Broker, options PublisherOptions) *Publisher {
+	if options.Codec == nil {
+		options.Codec = JSONCodec{}
+	}
+	return &Publisher{broker: broker, opts: options}
 }
 
 func (p *Publisher) PublishEvent(ctx context.Context, channel string, event Event) error {
+	payload, key, err := p.encode(ctx, channel, event)
+	if err != nil {
+		return err
+	}
+	if claimed, err := p.claim(ctx, key); err != nil || !claimed {
+		return err
+	}
+
+	if err := p.publish(ctx, channel, payload); err != nil {
+		p.release(ctx, key)
+		return err
+	}
+	return nil
+}
+
+// encode validates and marshals event. It fills in a missing ID and converts
+// TTL to ExpiresAt, and returns the key used for duplicate suppression.
+func (p *Publisher) encode(ctx context.Context, channel string, event Event) ([]byte, string, error) {
 	if channel == "" {
-		return errors.New("channel cannot be empty")
+		return nil, "", errors.New("channel cannot be empty")
 	}
 	if event.EventType == "" {
-		return errors.New("event type cannot be empty")
+		return nil, "", errors.New("event type cannot be empty")
+	}
+	if err := checkChannels(p.opts.ChannelValidation, ValidateChannel(channel), func(err error) {
+		if p.opts.OnError != nil {
+			p.opts.OnError(ctx, err)
+		}
+	}); err != nil {
+		return nil, "", err
 	}
 
-	payload, err := json.Marshal(event)
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.TTL > 0 && event.ExpiresAt.IsZero() {
+		event.ExpiresAt = time.Now().Add(event.TTL)
+	}
+	payload, err := p.opts.Codec.Marshal(event)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
+	payload, err = compressPayload(p.opts.Compression, payload)
+	if err != nil {
+		return nil, "", err
+	}
+	if p.opts.Keyring != nil {
+		if payload, err = p.opts.Keyring.seal(channel, payload); err != nil {
+			return nil, "", err
+		}
+	}
+	return payload, dedupKey(channel, event.ID), nil
+}
 
+// publish sends an encoded payload, numbered by the Sequencer when set.
+func (p *Publisher) publish(ctx context.Context, channel string, payload []byte) error {
+	if p.opts.Sequencer != nil {
+		return p.opts.Sequencer.PublishSequenced(ctx, channel, payload)
+	}
 	return p.broker.Publish(ctx, channel, payload)
 }
 
+func (p *Publisher) claim(ctx context.Context, key string) (bool, error) {
+	if p.opts.Deduplicator == nil {
+		return true, nil
+	}
+	return p.opts.Deduplicator.Claim(ctx, key)
+}
+
+func (p *Publisher) release(ctx context.Context, key string) {
+	if p.opts.Deduplicator == nil {
+		return
+	}
+	if err := p.opts.Deduplicator.Release(ctx, key); err != nil && p.opts.OnError != nil {
+		p.opts.OnError(ctx, err)
+	}
+}
+
 func (p *Publisher) PublishType(ctx context.Context, channel string, eventType string) error {
 	return p.PublishEvent(ctx, channel, Event{EventType: eventType})
 }
@@ -0,0 +1,145 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrBatchAborted is reported for the items of a batch that were not
+// published because another item failed to encode or claim.
+var ErrBatchAborted = errors.New("batch aborted")
+
+type PublishItem struct {
+	Channel string
+	Event   Event
+}
+
+// BatchError reports the items of a batch that failed. Errors has one entry
+// per item, nil for items that were published.
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d events failed to publish: %v", failed, len(e.Errors), first)
+}
+
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// PublishBatch publishes items in one broker round-trip when the broker
+// implements BatchBroker, and sequentially otherwise. If any item fails to
+// encode or claim, nothing is published and the other items report
+// ErrBatchAborted. A non-nil error is a *BatchError.
+func (p *Publisher) PublishBatch(ctx context.Context, items []PublishItem) error {
+	errs := make([]error, len(items))
+	keys := make([]string, len(items))
+	msgs := make([]BrokerMsg, 0, len(items))
+	idx := make([]int, 0, len(items))
+
+	for i, item := range items {
+		payload, key, err := p.encode(ctx, item.Channel, item.Event)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		claimed, err := p.claim(ctx, key)
+		if err != nil || !claimed {
+			errs[i] = err
+			continue
+		}
+		keys[i] = key
+		msgs = append(msgs, BrokerMsg{Channel: item.Channel, Payload: payload})
+		idx = append(idx, i)
+	}
+
+	if abortBatch(errs) {
+		for _, key := range keys {
+			if key != "" {
+				p.release(ctx, key)
+			}
+		}
+		return &BatchError{Errors: errs}
+	}
+
+	// Sequenced items are numbered one publish at a time.
+	if bb, ok := p.broker.(BatchBroker); ok && p.opts.Sequencer == nil && len(msgs) > 0 {
+		results := bb.PublishBatch(ctx, msgs)
+		if len(results) != len(msgs) {
+			err := errors.New("broker returned mismatched batch results")
+			for _, i := range idx {
+				errs[i] = err
+			}
+		} else {
+			for j, err := range results {
+				errs[idx[j]] = err
+			}
+		}
+	} else {
+		for j, msg := range msgs {
+			errs[idx[j]] = p.publish(ctx, msg.Channel, msg.Payload)
+		}
+	}
+
+	failed := false
+	for i, err := range errs {
+		if err != nil {
+			failed = true
+			if keys[i] != "" {
+				p.release(ctx, keys[i])
+			}
+		}
+	}
+	if failed {
+		return &BatchError{Errors: errs}
+	}
+	return nil
+}
+
+// abortBatch fills in ErrBatchAborted for the items that did not fail and
+// reports whether any item failed.
+func abortBatch(errs []error) bool {
+	failed := false
+	for _, err := range errs {
+		if err != nil {
+			failed = true
+			break
+		}
+	}
+	if !failed {
+		return false
+	}
+	for i, err := range errs {
+		if err == nil {
+			errs[i] = ErrBatchAborted
+		}
+	}
+	return true
+}
+
+// PublishMulti publishes the same event to every channel.
+func (p *Publisher) PublishMulti(ctx context.Context, channels []string, event Event) error {
+	items := make([]PublishItem, len(channels))
+	for i, channel := range channels {
+		items[i] = PublishItem{Channel: channel, Event: event}
+	}
+	return p.PublishBatch(ctx, items)
+}
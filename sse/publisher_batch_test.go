@@ -0,0 +1,108 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type batchTestBroker struct {
+	*testBroker
+	batches [][]BrokerMsg
+}
+
+func (b *batchTestBroker) PublishBatch(_ context.Context, msgs []BrokerMsg) []error {
+	b.batches = append(b.batches, msgs)
+	errs := make([]error, len(msgs))
+	for i, msg := range msgs {
+		if msg.Channel == "scope:1:broken" {
+			errs[i] = errors.New("broken channel")
+		}
+	}
+	return errs
+}
+
+type mapDeduplicator map[string]bool
+
+func (d mapDeduplicator) Claim(_ context.Context, key string) (bool, error) {
+	if d[key] {
+		return false, nil
+	}
+	d[key] = true
+	return true, nil
+}
+
+func (d mapDeduplicator) Release(_ context.Context, key string) error {
+	delete(d, key)
+	return nil
+}
+
+func TestPublisherPublishBatchUsesBatchBroker(t *testing.T) {
+	broker := &batchTestBroker{testBroker: newTestBroker()}
+	pub := NewPublisher(broker)
+
+	err := pub.PublishBatch(context.Background(), []PublishItem{
+		{Channel: "scope:1:students", Event: Event{EventType: "students.changed"}},
+		{Channel: "scope:1:broken", Event: Event{EventType: "students.changed"}},
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchError, got %v", err)
+	}
+	if len(batchErr.Errors) != 2 || batchErr.Errors[0] != nil || batchErr.Errors[1] == nil {
+		t.Fatalf("unexpected per-item errors: %v", batchErr.Errors)
+	}
+	if len(broker.batches) != 1 || len(broker.batches[0]) != 2 {
+		t.Fatalf("expected one batch of two messages, got %v", broker.batches)
+	}
+}
+
+func TestPublisherPublishBatchAbortsOnInvalidItem(t *testing.T) {
+	broker := &batchTestBroker{testBroker: newTestBroker()}
+	dedup := mapDeduplicator{}
+	pub := NewPublisherWithOptions(broker, PublisherOptions{Deduplicator: dedup})
+
+	err := pub.PublishBatch(context.Background(), []PublishItem{
+		{Channel: "scope:1:students", Event: Event{ID: "a", EventType: "students.changed"}},
+		{Channel: "scope:1:plans", Event: Event{}},
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchError, got %v", err)
+	}
+	if !errors.Is(batchErr.Errors[0], ErrBatchAborted) || batchErr.Errors[1] == nil || errors.Is(batchErr.Errors[1], ErrBatchAborted) {
+		t.Fatalf("unexpected per-item errors: %v", batchErr.Errors)
+	}
+	if len(broker.batches) != 0 {
+		t.Fatalf("expected nothing to be published, got %v", broker.batches)
+	}
+	if claimed, _ := dedup.Claim(context.Background(), dedupKey("scope:1:students", "a")); !claimed {
+		t.Fatal("expected the valid item's claim to be released")
+	}
+}
+
+func TestPublisherPublishMultiFallsBackToSequential(t *testing.T) {
+	broker := newTestBroker()
+	pub := NewPublisher(broker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	channels := []string{"scope:1:students", "scope:1:plans", "scope:1:dashboard"}
+	if err := pub.PublishMulti(context.Background(), channels, Event{EventType: "students.changed"}); err != nil {
+		t.Fatalf("publish multi failed: %v", err)
+	}
+
+	for _, want := range channels {
+		msg := <-sub.Channel()
+		if msg.Channel != want {
+			t.Fatalf("unexpected channel: %s", msg.Channel)
+		}
+	}
+}
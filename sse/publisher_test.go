@@ -3,6 +3,7 @@ package sse
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 )
@@ -65,3 +66,14 @@ func TestPublisherPublishType(t *testing.T) {
 		t.Fatalf("publish type failed: %v", err)
 	}
 }
+
+func TestPublisherPublishAtWithoutScheduler(t *testing.T) {
+	pub := NewPublisher(newTestBroker())
+
+	if _, err := pub.PublishAfter(context.Background(), time.Second, "scope:1:students", Event{EventType: "students.changed"}); !errors.Is(err, ErrNoScheduler) {
+		t.Fatalf("expected ErrNoScheduler, got %v", err)
+	}
+	if err := pub.CancelScheduled(context.Background(), "id"); !errors.Is(err, ErrNoScheduler) {
+		t.Fatalf("expected ErrNoScheduler, got %v", err)
+	}
+}
@@ -2,70 +2,325 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/PabloPavan/eventrail/sse"
 	"github.com/redis/go-redis/v9"
 )
 
+type BrokerPubSubOptions struct {
+	// AtomicBatches wraps PublishBatch in MULTI/EXEC so either every message
+	// of a batch is published or none is.
+	AtomicBatches bool
+	// SequenceKeyPrefix namespaces the INCR counters used by
+	// PublishSequenced.
+	SequenceKeyPrefix string
+	// SequenceTTL is how long a channel's counter is kept after its last
+	// publish. A counter that expires restarts at 1, which hubs treat as a
+	// reset. Default 24h.
+	SequenceTTL time.Duration
+	// Connections is the number of PubSub connections shared by all
+	// subscriptions; patterns are spread across them by hash. Default 1.
+	Connections int
+	// Logger reports messages dropped because a subscriber was full.
+	// Default discards.
+	Logger *slog.Logger
+	// DropLevel is the level of dropped message records. Default warn.
+	DropLevel slog.Leveler
+	// OnError receives ErrSubscriberFull for every dropped message. Hubs
+	// learn about drops from BrokerMsg.Dropped.
+	OnError func(ctx context.Context, err error)
+}
+
+// ErrSubscriberFull reports a message dropped because a subscription's
+// buffer was full.
+var ErrSubscriberFull = errors.New("subscriber full, message dropped")
+
 type BrokerPubSub struct {
 	redisClient *redis.Client
+	opts        BrokerPubSubOptions
+
+	// subMu serializes subscription changes and the PSUBSCRIBE/PUNSUBSCRIBE
+	// calls they make, and guards conns. mu only guards the maps read by
+	// dispatch, so delivery never waits on network I/O.
+	subMu sync.Mutex
+	conns []*redis.PubSub
+
+	mu        sync.RWMutex
+	refs      map[string]int
+	byPattern map[string]map[*redisSubscription]struct{}
 }
 
 func NewBrokerPubSub(redisClient *redis.Client) *BrokerPubSub {
-	return &BrokerPubSub{redisClient: redisClient}
+	return NewBrokerPubSubWithOptions(redisClient, BrokerPubSubOptions{})
+}
+
+func NewBrokerPubSubWithOptions(redisClient *redis.Client, options BrokerPubSubOptions) *BrokerPubSub {
+	if options.SequenceKeyPrefix == "" {
+		options.SequenceKeyPrefix = "eventrail:seq"
+	}
+	if options.SequenceTTL <= 0 {
+		options.SequenceTTL = 24 * time.Hour
+	}
+	if options.Connections <= 0 {
+		options.Connections = 1
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.DiscardHandler)
+	}
+	if options.DropLevel == nil {
+		options.DropLevel = slog.LevelWarn
+	}
+	return &BrokerPubSub{
+		redisClient: redisClient,
+		opts:        options,
+		conns:       make([]*redis.PubSub, options.Connections),
+		refs:        make(map[string]int),
+		byPattern:   make(map[string]map[*redisSubscription]struct{}),
+	}
 }
 
 type redisSubscription struct {
-	pubsub *redis.PubSub
-	ch     <-chan *redis.Message
-	out    chan sse.BrokerMsg
-	ctx    context.Context
-	cancel context.CancelFunc
+	broker   *BrokerPubSub
+	patterns []string
+	out      chan sse.BrokerMsg
+	closed   bool // guarded by broker.mu
+	dropped  atomic.Int64
 }
 
+// Subscribe registers patterns on the broker's shared PubSub connections.
+// A pattern is PSUBSCRIBEd when its first subscriber arrives and
+// PUNSUBSCRIBEd when its last one closes; messages are demultiplexed to
+// subscriptions locally.
 func (b *BrokerPubSub) Subscribe(ctx context.Context, patterns ...string) (sse.Subscription, error) {
-	pubsub := b.redisClient.PSubscribe(ctx, patterns...)
-	cctx, cancel := context.WithCancel(ctx)
+	if ctx == nil {
+		return nil, errors.New("context cannot be nil")
+	}
 
 	sub := &redisSubscription{
-		pubsub: pubsub,
-		ch:     pubsub.Channel(),
-		out:    make(chan sse.BrokerMsg, 128),
-		ctx:    cctx,
-		cancel: cancel,
+		broker:   b,
+		patterns: uniquePatterns(patterns),
+		out:      make(chan sse.BrokerMsg, 128),
 	}
 
-	go sub.forwardMessages()
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	var added []string
+	for _, pattern := range sub.patterns {
+		b.mu.RLock()
+		refs := b.refs[pattern]
+		b.mu.RUnlock()
+		if refs > 0 {
+			continue
+		}
+		if err := b.conn(pattern).PSubscribe(ctx, pattern); err != nil {
+			for _, p := range added {
+				_ = b.conn(p).PUnsubscribe(context.Background(), p)
+			}
+			return nil, err
+		}
+		added = append(added, pattern)
+	}
+
+	b.mu.Lock()
+	for _, pattern := range sub.patterns {
+		b.refs[pattern]++
+		if b.byPattern[pattern] == nil {
+			b.byPattern[pattern] = make(map[*redisSubscription]struct{})
+		}
+		b.byPattern[pattern][sub] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		_ = sub.Close()
+	}()
 
 	return sub, nil
 }
 
-func (r *redisSubscription) forwardMessages() {
-	defer close(r.out)
-	for {
-		select {
-		case <-r.ctx.Done():
-			return
-		case msg, ok := <-r.ch:
-			if !ok {
-				return
+// conn returns the shared connection responsible for pattern, opening it on
+// first use. Callers must hold b.subMu.
+func (b *BrokerPubSub) conn(pattern string) *redis.PubSub {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(pattern))
+	idx := int(h.Sum32() % uint32(len(b.conns)))
+
+	if b.conns[idx] == nil {
+		pubsub := b.redisClient.PSubscribe(context.Background())
+		b.conns[idx] = pubsub
+		go b.dispatch(pubsub)
+	}
+	return b.conns[idx]
+}
+
+func (b *BrokerPubSub) dispatch(pubsub *redis.PubSub) {
+	for msg := range pubsub.Channel() {
+		out := sse.BrokerMsg{
+			Pattern: msg.Pattern,
+			Channel: msg.Channel,
+			Payload: []byte(msg.Payload),
+		}
+
+		b.mu.RLock()
+		for sub := range b.byPattern[msg.Pattern] {
+			// A full subscriber must not stall every other hub on the
+			// connection; the drop count travels with its next message.
+			dropped := sub.dropped.Load()
+			out.Dropped = int(dropped)
+			select {
+			case sub.out <- out:
+				sub.dropped.Add(-dropped)
+			default:
+				sub.dropped.Add(1)
+				b.opts.Logger.Log(context.Background(), b.opts.DropLevel.Level(), "subscriber full, message dropped",
+					"channel", msg.Channel, "pattern", msg.Pattern)
+				if b.opts.OnError != nil {
+					b.opts.OnError(context.Background(), fmt.Errorf("%w: %s", ErrSubscriberFull, msg.Channel))
+				}
 			}
-			r.out <- sse.BrokerMsg{
-				Pattern: msg.Pattern,
-				Channel: msg.Channel,
-				Payload: []byte(msg.Payload),
+		}
+		b.mu.RUnlock()
+	}
+}
+
+// releaseLocked drops sub's references and returns the patterns nobody else
+// uses. Callers must hold b.mu.
+func (b *BrokerPubSub) releaseLocked(sub *redisSubscription) []string {
+	var unused []string
+	for _, pattern := range sub.patterns {
+		subs, ok := b.byPattern[pattern]
+		if !ok {
+			continue
+		}
+		if _, ok := subs[sub]; !ok {
+			continue
+		}
+		delete(subs, sub)
+		b.refs[pattern]--
+		if b.refs[pattern] > 0 {
+			continue
+		}
+		delete(b.refs, pattern)
+		delete(b.byPattern, pattern)
+		unused = append(unused, pattern)
+	}
+	return unused
+}
+
+// Close closes the shared connections. Open subscriptions stop receiving
+// messages.
+func (b *BrokerPubSub) Close() error {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var errs []error
+	for i, pubsub := range b.conns {
+		if pubsub == nil {
+			continue
+		}
+		errs = append(errs, pubsub.Close())
+		b.conns[i] = nil
+	}
+	b.refs = make(map[string]int)
+	for _, subs := range b.byPattern {
+		for sub := range subs {
+			if !sub.closed {
+				sub.closed = true
+				close(sub.out)
 			}
 		}
 	}
+	b.byPattern = make(map[string]map[*redisSubscription]struct{})
+	return errors.Join(errs...)
 }
 
 func (r *redisSubscription) Channel() <-chan sse.BrokerMsg { return r.out }
 
 func (r *redisSubscription) Close() error {
-	r.cancel()
-	return r.pubsub.Close()
+	b := r.broker
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.mu.Lock()
+	if r.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	unused := b.releaseLocked(r)
+	close(r.out)
+	b.mu.Unlock()
+
+	var errs []error
+	for _, pattern := range unused {
+		if err := b.conn(pattern).PUnsubscribe(context.Background(), pattern); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func uniquePatterns(patterns []string) []string {
+	seen := make(map[string]struct{}, len(patterns))
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	return out
 }
 
 func (b *BrokerPubSub) Publish(ctx context.Context, channel string, payload []byte) error {
 	return b.redisClient.Publish(ctx, channel, payload).Err()
 }
+
+func (b *BrokerPubSub) PublishBatch(ctx context.Context, msgs []sse.BrokerMsg) []error {
+	pipe := b.redisClient.Pipeline()
+	if b.opts.AtomicBatches {
+		pipe = b.redisClient.TxPipeline()
+	}
+
+	cmds := make([]*redis.IntCmd, len(msgs))
+	for i, msg := range msgs {
+		cmds[i] = pipe.Publish(ctx, msg.Channel, msg.Payload)
+	}
+	_, execErr := pipe.Exec(ctx)
+
+	errs := make([]error, len(msgs))
+	for i, cmd := range cmds {
+		errs[i] = cmd.Err()
+		if errs[i] == nil && execErr != nil && b.opts.AtomicBatches {
+			errs[i] = execErr
+		}
+	}
+	return errs
+}
+
+// publishSequencedScript increments the channel's counter and publishes the
+// framed payload in one step, so subscribers receive numbers in order.
+var publishSequencedScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+redis.call('PUBLISH', ARGV[1], ARGV[2] .. seq .. '\n' .. ARGV[3])
+return seq
+`)
+
+func (b *BrokerPubSub) PublishSequenced(ctx context.Context, channel string, payload []byte) error {
+	return publishSequencedScript.Run(ctx, b.redisClient,
+		[]string{b.opts.SequenceKeyPrefix + ":" + channel}, channel, sse.SequenceMarker, payload,
+		b.opts.SequenceTTL.Milliseconds()).Err()
+}
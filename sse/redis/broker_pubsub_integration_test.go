@@ -50,7 +50,7 @@ func TestBrokerPubSubIntegrationRedis(t *testing.T) {
 	_ = sub.Close()
 }
 
-func waitForRedis(ctx context.Context, client *redis.Client, timeout time.Duration) error {
+func waitForRedis(ctx context.Context, client redis.UniversalClient, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for {
 		if err := client.Ping(ctx).Err(); err == nil {
@@ -62,3 +62,47 @@ func waitForRedis(ctx context.Context, client *redis.Client, timeout time.Durati
 		time.Sleep(50 * time.Millisecond)
 	}
 }
+
+func TestBrokerShardedIntegrationRedis(t *testing.T) {
+	addr := os.Getenv("INTEGRATION_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("INTEGRATION_REDIS_ADDR not set")
+	}
+
+	rdb := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{addr}})
+	if err := waitForRedis(context.Background(), rdb, 2*time.Second); err != nil {
+		t.Fatalf("redis not ready: %v", err)
+	}
+
+	broker := NewBrokerSharded(rdb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := broker.Subscribe(ctx, "scope:1:stud*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if err := broker.Publish(context.Background(), "scope:1:plans", []byte("ignored")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("hello")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		if msg.Channel != "scope:1:students" || msg.Pattern != "scope:1:stud*" {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+		if string(msg.Payload) != "hello" {
+			t.Fatalf("unexpected payload: %s", string(msg.Payload))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+
+	_ = sub.Close()
+}
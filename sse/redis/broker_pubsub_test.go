@@ -1,10 +1,16 @@
 package redis
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/PabloPavan/eventrail/sse"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
 )
@@ -83,3 +89,211 @@ func TestBrokerPubSubCloseClosesChannel(t *testing.T) {
 		t.Fatal("timeout waiting for channel close")
 	}
 }
+
+func TestBrokerPubSubPublishBatch(t *testing.T) {
+	for _, atomic := range []bool{false, true} {
+		mr, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("failed to start miniredis: %v", err)
+		}
+
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		broker := NewBrokerPubSubWithOptions(rdb, BrokerPubSubOptions{AtomicBatches: atomic})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		sub, err := broker.Subscribe(ctx, "scope:1:*")
+		if err != nil {
+			t.Fatalf("subscribe failed: %v", err)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+
+		errs := broker.PublishBatch(context.Background(), []sse.BrokerMsg{
+			{Channel: "scope:1:students", Payload: []byte("a")},
+			{Channel: "scope:1:plans", Payload: []byte("b")},
+		})
+		if len(errs) != 2 || errs[0] != nil || errs[1] != nil {
+			t.Fatalf("unexpected errors (atomic=%v): %v", atomic, errs)
+		}
+
+		for _, want := range []string{"scope:1:students", "scope:1:plans"} {
+			select {
+			case msg := <-sub.Channel():
+				if msg.Channel != want {
+					t.Fatalf("unexpected channel: %s", msg.Channel)
+				}
+			case <-time.After(1 * time.Second):
+				t.Fatal("timeout waiting for message")
+			}
+		}
+
+		_ = sub.Close()
+		cancel()
+		mr.Close()
+	}
+}
+
+func TestBrokerPubSubPublishSequenced(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	broker := NewBrokerPubSub(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer broker.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := broker.PublishSequenced(context.Background(), "scope:1:students", []byte("x")); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	for _, want := range []string{"\x00s1\nx", "\x00s2\nx"} {
+		select {
+		case msg := <-sub.Channel():
+			if string(msg.Payload) != want {
+				t.Fatalf("unexpected payload: %q, want %q", msg.Payload, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+	if got, _ := mr.Get("eventrail:seq:scope:1:students"); got != "2" {
+		t.Fatalf("unexpected counter value: %s", got)
+	}
+	if ttl := mr.TTL("eventrail:seq:scope:1:students"); ttl != 24*time.Hour {
+		t.Fatalf("unexpected counter TTL: %v", ttl)
+	}
+}
+
+func TestBrokerPubSubSharesPatternsAcrossSubscriptions(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	broker := NewBrokerPubSub(rdb)
+	defer broker.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	b, err := broker.Subscribe(ctx, "scope:1:*", "scope:2:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	waitForNumPat(t, rdb, 2)
+
+	if err := broker.Publish(context.Background(), "scope:1:students", []byte("hello")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	for _, sub := range []interface {
+		Channel() <-chan sse.BrokerMsg
+	}{a, b} {
+		select {
+		case msg := <-sub.Channel():
+			if msg.Channel != "scope:1:students" {
+				t.Fatalf("unexpected channel: %s", msg.Channel)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	waitForNumPat(t, rdb, 1)
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	waitForNumPat(t, rdb, 0)
+}
+
+func waitForNumPat(t *testing.T, rdb *redis.Client, want int64) {
+	t.Helper()
+
+	deadline := time.Now().Add(1 * time.Second)
+	for {
+		n, err := rdb.PubSubNumPat(context.Background()).Result()
+		if err == nil && n == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected pattern count: %d, want %d (err %v)", n, want, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBrokerPubSubReportsDrops(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	var reported atomic.Int64
+	var logs bytes.Buffer
+	broker := NewBrokerPubSubWithOptions(redis.NewClient(&redis.Options{Addr: mr.Addr()}), BrokerPubSubOptions{
+		Logger:    slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		DropLevel: slog.LevelDebug,
+		OnError: func(_ context.Context, err error) {
+			if errors.Is(err, ErrSubscriberFull) {
+				reported.Add(1)
+			}
+		},
+	})
+	defer broker.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	buffered := cap(sub.(*redisSubscription).out)
+	for i := 0; i < buffered+2; i++ {
+		_ = broker.Publish(context.Background(), "scope:1:students", []byte("x"))
+	}
+	deadline := time.Now().Add(time.Second)
+	for reported.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 reported drops, got %d", reported.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(logs.String(), `level=DEBUG msg="subscriber full, message dropped"`) {
+		t.Fatalf("expected drop logged at debug, got %q", logs.String())
+	}
+
+	for i := 0; i < buffered; i++ {
+		<-sub.Channel()
+	}
+	_ = broker.Publish(context.Background(), "scope:1:students", []byte("y"))
+	select {
+	case msg := <-sub.Channel():
+		if msg.Dropped != 2 || string(msg.Payload) != "y" {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
@@ -0,0 +1,297 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/redis/go-redis/v9"
+)
+
+type BrokerShardedOptions struct {
+	// ChannelPrefix is prepended to every shard channel. Default "eventrail".
+	ChannelPrefix string
+	// Logger reports messages dropped because a subscriber was full.
+	// Default discards.
+	Logger *slog.Logger
+	// DropLevel is the level of dropped message records. Default warn.
+	DropLevel slog.Leveler
+	// OnError receives ErrSubscriberFull for every dropped message. Hubs
+	// learn about drops from BrokerMsg.Dropped.
+	OnError func(ctx context.Context, err error)
+}
+
+// BrokerSharded delivers events with SPUBLISH/SSUBSCRIBE so that, in Redis
+// Cluster, a message only travels to the shard owning its scope instead of
+// being broadcast to every node.
+//
+// Sharded Pub/Sub has no pattern support, so every topic of a scope shares
+// one shard channel, {ChannelPrefix}:{scope:scope_id}, whose hash tag pins it
+// to one slot. The original channel travels with the payload and patterns are
+// matched locally. As a consequence:
+//
+//   - published channels must follow {scope}:{scope_id}:{topic};
+//   - subscription patterns may only use wildcards in the topic segment;
+//   - channels cannot contain newlines.
+//
+// Each shard channel has one connection per process, shared by every hub of
+// that scope and closed with its last subscription, so an instance holds one
+// connection per active scope.
+type BrokerSharded struct {
+	client redis.UniversalClient
+	opts   BrokerShardedOptions
+
+	// subMu serializes subscription changes and the connections they open
+	// and close. mu only guards the maps read by dispatch.
+	subMu  sync.Mutex
+	mu     sync.RWMutex
+	shards map[string]*shardConn
+}
+
+func NewBrokerSharded(client redis.UniversalClient) *BrokerSharded {
+	return NewBrokerShardedWithOptions(client, BrokerShardedOptions{})
+}
+
+func NewBrokerShardedWithOptions(client redis.UniversalClient, options BrokerShardedOptions) *BrokerSharded {
+	if options.ChannelPrefix == "" {
+		options.ChannelPrefix = "eventrail"
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.DiscardHandler)
+	}
+	if options.DropLevel == nil {
+		options.DropLevel = slog.LevelWarn
+	}
+	return &BrokerSharded{
+		client: client,
+		opts:   options,
+		shards: make(map[string]*shardConn),
+	}
+}
+
+// shardConn is the connection for one shard channel and the patterns each
+// subscription registered on it.
+type shardConn struct {
+	pubsub *redis.PubSub
+	subs   map[*shardedSubscription][]string
+}
+
+type shardedSubscription struct {
+	broker  *BrokerSharded
+	shards  []string
+	out     chan sse.BrokerMsg
+	closed  bool // guarded by broker.mu
+	dropped atomic.Int64
+}
+
+func (b *BrokerSharded) Subscribe(ctx context.Context, patterns ...string) (sse.Subscription, error) {
+	if ctx == nil {
+		return nil, errors.New("context cannot be nil")
+	}
+
+	byShard := make(map[string][]string)
+	for _, pattern := range patterns {
+		shard, err := b.shardChannel(pattern)
+		if err != nil {
+			return nil, err
+		}
+		byShard[shard] = append(byShard[shard], pattern)
+	}
+
+	sub := &shardedSubscription{
+		broker: b,
+		out:    make(chan sse.BrokerMsg, 128),
+	}
+
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	// In a cluster each shard channel may live on a different node, so it
+	// gets its own connection.
+	opened := make(map[string]*shardConn)
+	for shard := range byShard {
+		b.mu.RLock()
+		_, exists := b.shards[shard]
+		b.mu.RUnlock()
+		if !exists {
+			opened[shard] = &shardConn{
+				pubsub: b.client.SSubscribe(context.Background(), shard),
+				subs:   make(map[*shardedSubscription][]string),
+			}
+		}
+	}
+
+	b.mu.Lock()
+	for shard, conn := range opened {
+		b.shards[shard] = conn
+	}
+	for shard, shardPatterns := range byShard {
+		b.shards[shard].subs[sub] = shardPatterns
+		sub.shards = append(sub.shards, shard)
+	}
+	b.mu.Unlock()
+
+	for _, conn := range opened {
+		go b.dispatch(conn)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = sub.Close()
+	}()
+
+	return sub, nil
+}
+
+func (b *BrokerSharded) dispatch(conn *shardConn) {
+	for msg := range conn.pubsub.Channel() {
+		channel, payload, ok := decodeShardedPayload([]byte(msg.Payload))
+		if !ok {
+			continue
+		}
+		b.deliver(conn, channel, payload)
+	}
+}
+
+// deliver fans a message out to the subscriptions of conn whose patterns
+// match channel.
+func (b *BrokerSharded) deliver(conn *shardConn, channel string, payload []byte) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub, patterns := range conn.subs {
+		pattern, ok := matchPattern(patterns, channel)
+		if !ok {
+			continue
+		}
+		// A full subscriber must not stall the other hubs of the scope; the
+		// drop count travels with its next message.
+		dropped := sub.dropped.Load()
+		select {
+		case sub.out <- sse.BrokerMsg{Pattern: pattern, Channel: channel, Payload: payload, Dropped: int(dropped)}:
+			sub.dropped.Add(-dropped)
+		default:
+			sub.dropped.Add(1)
+			b.opts.Logger.Log(context.Background(), b.opts.DropLevel.Level(), "subscriber full, message dropped",
+				"channel", channel, "pattern", pattern)
+			if b.opts.OnError != nil {
+				b.opts.OnError(context.Background(), fmt.Errorf("%w: %s", ErrSubscriberFull, channel))
+			}
+		}
+	}
+}
+
+// Close closes the shared connections. Open subscriptions stop receiving
+// messages.
+func (b *BrokerSharded) Close() error {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.mu.Lock()
+	var conns []*shardConn
+	for _, conn := range b.shards {
+		conns = append(conns, conn)
+		for sub := range conn.subs {
+			if !sub.closed {
+				sub.closed = true
+				close(sub.out)
+			}
+		}
+	}
+	b.shards = make(map[string]*shardConn)
+	b.mu.Unlock()
+
+	var errs []error
+	for _, conn := range conns {
+		errs = append(errs, conn.pubsub.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (b *BrokerSharded) Publish(ctx context.Context, channel string, payload []byte) error {
+	shard, err := b.shardChannel(channel)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(channel, "\n") {
+		return fmt.Errorf("channel %q cannot contain newlines", channel)
+	}
+	return b.client.SPublish(ctx, shard, encodeShardedPayload(channel, payload)).Err()
+}
+
+func (b *BrokerSharded) shardChannel(channel string) (string, error) {
+	c, err := sse.ParseChannel(channel)
+	if err != nil {
+		return "", err
+	}
+	return b.opts.ChannelPrefix + ":{" + c.Scope + ":" + strconv.FormatInt(c.ScopeID, 10) + "}", nil
+}
+
+func (s *shardedSubscription) Channel() <-chan sse.BrokerMsg { return s.out }
+
+func (s *shardedSubscription) Close() error {
+	b := s.broker
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.mu.Lock()
+	if s.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	var unused []*shardConn
+	for _, shard := range s.shards {
+		conn, ok := b.shards[shard]
+		if !ok {
+			continue
+		}
+		delete(conn.subs, s)
+		if len(conn.subs) == 0 {
+			delete(b.shards, shard)
+			unused = append(unused, conn)
+		}
+	}
+	close(s.out)
+	b.mu.Unlock()
+
+	var errs []error
+	for _, conn := range unused {
+		errs = append(errs, conn.pubsub.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func encodeShardedPayload(channel string, payload []byte) []byte {
+	out := make([]byte, 0, len(channel)+1+len(payload))
+	out = append(out, channel...)
+	out = append(out, '\n')
+	return append(out, payload...)
+}
+
+func decodeShardedPayload(raw []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(raw, '\n')
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(raw[:i]), raw[i+1:], true
+}
+
+func matchPattern(patterns []string, channel string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, channel); err == nil && ok {
+			return pattern, true
+		}
+		if pattern == channel {
+			return pattern, true
+		}
+	}
+	return "", false
+}
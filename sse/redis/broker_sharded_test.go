@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestBrokerShardedShardChannel(t *testing.T) {
+	broker := NewBrokerSharded(nil)
+
+	for _, channel := range []string{"gym:1:students", "gym:1:*", "gym:1:plans"} {
+		shard, err := broker.shardChannel(channel)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", channel, err)
+		}
+		if shard != "eventrail:{gym:1}" {
+			t.Fatalf("unexpected shard channel for %s: %s", channel, shard)
+		}
+	}
+
+	for _, bad := range []string{"*", "gym:*:students", "global"} {
+		if _, err := broker.shardChannel(bad); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestShardedPayloadRoundTrip(t *testing.T) {
+	raw := encodeShardedPayload("gym:1:students", []byte("hello\nworld"))
+
+	channel, payload, ok := decodeShardedPayload(raw)
+	if !ok {
+		t.Fatal("expected payload to decode")
+	}
+	if channel != "gym:1:students" || string(payload) != "hello\nworld" {
+		t.Fatalf("unexpected decode: %s %q", channel, payload)
+	}
+
+	if _, _, ok := decodeShardedPayload([]byte("no separator")); ok {
+		t.Fatal("expected malformed payload to be rejected")
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	pattern, ok := matchPattern([]string{"gym:1:plans", "gym:1:stud*"}, "gym:1:students")
+	if !ok || pattern != "gym:1:stud*" {
+		t.Fatalf("unexpected match: %s %v", pattern, ok)
+	}
+	if _, ok := matchPattern([]string{"gym:1:plans"}, "gym:1:students"); ok {
+		t.Fatal("expected no match")
+	}
+}
+
+func TestBrokerShardedSharesConnectionsPerShard(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	broker := NewBrokerSharded(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer broker.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := broker.Subscribe(ctx, "gym:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	b, err := broker.Subscribe(ctx, "gym:1:students", "gym:2:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	broker.mu.RLock()
+	shards := len(broker.shards)
+	conn := broker.shards["eventrail:{gym:1}"]
+	broker.mu.RUnlock()
+	if shards != 2 || len(conn.subs) != 2 {
+		t.Fatalf("expected 2 shared connections and 2 subscriptions on gym:1, got %d and %d", shards, len(conn.subs))
+	}
+
+	broker.deliver(conn, "gym:1:plans", []byte("plans"))
+	broker.deliver(conn, "gym:1:students", []byte("students"))
+	if msg := <-a.Channel(); msg.Pattern != "gym:1:*" || string(msg.Payload) != "plans" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg := <-a.Channel(); string(msg.Payload) != "students" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg := <-b.Channel(); msg.Pattern != "gym:1:students" || string(msg.Payload) != "students" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	broker.mu.RLock()
+	shards = len(broker.shards)
+	broker.mu.RUnlock()
+	if shards != 1 {
+		t.Fatalf("expected the gym:2 connection to be closed, %d left", shards)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	broker.mu.RLock()
+	shards = len(broker.shards)
+	broker.mu.RUnlock()
+	if shards != 0 {
+		t.Fatalf("expected every connection to be closed, %d left", shards)
+	}
+}
+
+func TestBrokerShardedReportsDrops(t *testing.T) {
+	var reported int
+	broker := NewBrokerShardedWithOptions(nil, BrokerShardedOptions{
+		OnError: func(_ context.Context, err error) {
+			if errors.Is(err, ErrSubscriberFull) {
+				reported++
+			}
+		},
+	})
+	sub := &shardedSubscription{broker: broker, out: make(chan sse.BrokerMsg, 1)}
+	conn := &shardConn{subs: map[*shardedSubscription][]string{sub: {"gym:1:*"}}}
+
+	for _, p := range []string{"a", "b", "c"} {
+		broker.deliver(conn, "gym:1:students", []byte(p))
+	}
+	if reported != 2 {
+		t.Fatalf("expected 2 reported drops, got %d", reported)
+	}
+	<-sub.out
+	broker.deliver(conn, "gym:1:students", []byte("d"))
+	if msg := <-sub.out; msg.Dropped != 2 || string(msg.Payload) != "d" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type DeduplicatorOptions struct {
+	KeyPrefix string
+	// TTL is how long a published event ID is remembered.
+	TTL time.Duration
+}
+
+// Deduplicator claims event IDs with SET NX so a retried publish from any
+// instance is suppressed for TTL.
+type Deduplicator struct {
+	redisClient *redis.Client
+	opts        DeduplicatorOptions
+}
+
+func NewDeduplicator(redisClient *redis.Client, options DeduplicatorOptions) *Deduplicator {
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "eventrail:dedup"
+	}
+	if options.TTL == 0 {
+		options.TTL = 10 * time.Minute
+	}
+	return &Deduplicator{redisClient: redisClient, opts: options}
+}
+
+func (d *Deduplicator) Claim(ctx context.Context, key string) (bool, error) {
+	return d.redisClient.SetNX(ctx, d.opts.KeyPrefix+":"+key, 1, d.opts.TTL).Result()
+}
+
+func (d *Deduplicator) Release(ctx context.Context, key string) error {
+	return d.redisClient.Del(ctx, d.opts.KeyPrefix+":"+key).Err()
+}
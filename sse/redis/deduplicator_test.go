@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDeduplicatorSuppressesRepeatedPublish(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	broker := NewBrokerPubSub(rdb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	dedup := NewDeduplicator(rdb, DeduplicatorOptions{})
+	a := sse.NewPublisherWithOptions(broker, sse.PublisherOptions{Deduplicator: dedup})
+	b := sse.NewPublisherWithOptions(broker, sse.PublisherOptions{Deduplicator: dedup})
+
+	evt := sse.Event{ID: "evt-1", EventType: "students.changed"}
+	if err := a.PublishEvent(context.Background(), "scope:1:students", evt); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := b.PublishEvent(context.Background(), "scope:1:students", evt); err != nil {
+		t.Fatalf("duplicate publish failed: %v", err)
+	}
+
+	select {
+	case <-sub.Channel():
+	case <-time.After(1 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	select {
+	case msg := <-sub.Channel():
+		t.Fatalf("duplicate delivered: %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDeduplicatorRelease(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	dedup := NewDeduplicator(redis.NewClient(&redis.Options{Addr: mr.Addr()}), DeduplicatorOptions{})
+
+	if ok, err := dedup.Claim(context.Background(), "k"); err != nil || !ok {
+		t.Fatalf("expected first claim to succeed: %v %v", ok, err)
+	}
+	if ok, _ := dedup.Claim(context.Background(), "k"); ok {
+		t.Fatal("expected second claim to fail")
+	}
+	if err := dedup.Release(context.Background(), "k"); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if ok, _ := dedup.Claim(context.Background(), "k"); !ok {
+		t.Fatal("expected claim after release to succeed")
+	}
+}
@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/redis/go-redis/v9"
+)
+
+// claimScript removes due events and returns them as channel, payload
+// pairs in one atomic step, so each event is claimed by exactly one instance
+// no matter how many poll.
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local out = {}
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local channel = redis.call('HGET', KEYS[2], id)
+	local payload = redis.call('HGET', KEYS[3], id)
+	redis.call('HDEL', KEYS[2], id)
+	redis.call('HDEL', KEYS[3], id)
+	if channel and payload then
+		table.insert(out, channel)
+		table.insert(out, payload)
+	end
+end
+return {#ids, out}
+`)
+
+var scheduleScript = redis.NewScript(`
+redis.call('HSET', KEYS[2], ARGV[2], ARGV[3])
+redis.call('HSET', KEYS[3], ARGV[2], ARGV[4])
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+var cancelScript = redis.NewScript(`
+local removed = redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return removed
+`)
+
+type SchedulerOptions struct {
+	Context      context.Context
+	KeyPrefix    string
+	PollInterval time.Duration
+	BatchSize    int
+	OnError      func(ctx context.Context, err error)
+}
+
+// Scheduler stores pending events in a Redis sorted set scored by due time.
+// Every instance may run one; each due event is claimed by exactly one
+// instance and published through its broker. An event whose publish fails
+// after the claim is reported to OnError and not retried.
+type Scheduler struct {
+	redisClient *redis.Client
+	broker      sse.Broker
+	opts        SchedulerOptions
+	keys        []string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewScheduler(redisClient *redis.Client, broker sse.Broker, options SchedulerOptions) *Scheduler {
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+	if options.KeyPrefix == "" {
+		options.KeyPrefix = "eventrail:schedule"
+	}
+	if options.PollInterval == 0 {
+		options.PollInterval = time.Second
+	}
+	if options.BatchSize == 0 {
+		options.BatchSize = 100
+	}
+
+	ctx, cancel := context.WithCancel(options.Context)
+	s := &Scheduler{
+		redisClient: redisClient,
+		broker:      broker,
+		opts:        options,
+		keys: []string{
+			options.KeyPrefix + ":due",
+			options.KeyPrefix + ":channels",
+			options.KeyPrefix + ":payloads",
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go s.poll(ctx)
+	return s
+}
+
+func (s *Scheduler) Schedule(ctx context.Context, at time.Time, channel string, payload []byte) (string, error) {
+	id := sse.NewScheduleID()
+	if err := scheduleScript.Run(ctx, s.redisClient, s.keys, at.UnixMilli(), id, channel, payload).Err(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *Scheduler) Cancel(ctx context.Context, id string) error {
+	removed, err := cancelScript.Run(ctx, s.redisClient, s.keys, id).Int()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return sse.ErrScheduleNotFound
+	}
+	return nil
+}
+
+// Close stops polling, after publishing events already claimed. Pending
+// events stay in Redis for other instances.
+func (s *Scheduler) Close() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *Scheduler) poll(ctx context.Context) {
+	defer close(s.done)
+
+	t := time.NewTicker(s.opts.PollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.dispatchDue(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) dispatchDue(ctx context.Context) {
+	for {
+		res, err := claimScript.Run(ctx, s.redisClient, s.keys, time.Now().UnixMilli(), s.opts.BatchSize).Slice()
+		if err != nil {
+			s.reportError(ctx, err)
+			return
+		}
+		n, _ := res[0].(int64)
+		due, _ := res[1].([]any)
+		// Claimed events are gone from Redis, so Close must not cancel their
+		// publish; it waits for the batch instead.
+		pubCtx := context.WithoutCancel(ctx)
+		for i := 0; i+1 < len(due); i += 2 {
+			channel, _ := due[i].(string)
+			payload, _ := due[i+1].(string)
+			if err := sse.PublishScheduled(pubCtx, s.broker, channel, []byte(payload)); err != nil {
+				s.reportError(pubCtx, err)
+			}
+		}
+		if int(n) < s.opts.BatchSize {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) reportError(ctx context.Context, err error) {
+	if ctx.Err() == nil && s.opts.OnError != nil {
+		s.opts.OnError(ctx, err)
+	}
+}
@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/eventrail/sse"
+	"github.com/PabloPavan/eventrail/sse/memory"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSchedulerDispatchesOnceAcrossInstances(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	broker := NewBrokerPubSub(rdb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	a := NewScheduler(rdb, broker, SchedulerOptions{PollInterval: 10 * time.Millisecond})
+	defer a.Close()
+	b := NewScheduler(rdb, broker, SchedulerOptions{PollInterval: 10 * time.Millisecond})
+	defer b.Close()
+
+	pub := sse.NewPublisherWithOptions(broker, sse.PublisherOptions{Scheduler: a})
+	if _, err := pub.PublishAfter(context.Background(), 30*time.Millisecond, "scope:1:subscriptions", sse.Event{EventType: "subscription.expiring"}); err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		if msg.Channel != "scope:1:subscriptions" {
+			t.Fatalf("unexpected channel: %s", msg.Channel)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("timeout waiting for scheduled event")
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		t.Fatalf("event dispatched twice: %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	scheduler := NewScheduler(rdb, NewBrokerPubSub(rdb), SchedulerOptions{PollInterval: 10 * time.Millisecond})
+	defer scheduler.Close()
+
+	id, err := scheduler.Schedule(context.Background(), time.Now().Add(time.Hour), "scope:1:students", []byte("hello"))
+	if err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+	if err := scheduler.Cancel(context.Background(), id); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if err := scheduler.Cancel(context.Background(), id); !errors.Is(err, sse.ErrScheduleNotFound) {
+		t.Fatalf("expected ErrScheduleNotFound, got %v", err)
+	}
+	if n, _ := rdb.ZCard(context.Background(), "eventrail:schedule:due").Result(); n != 0 {
+		t.Fatalf("expected no pending events, got %d", n)
+	}
+}
+
+func TestSchedulerPublishesThroughBroker(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	broker := memory.NewBrokerInMemory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	scheduler := NewScheduler(rdb, broker, SchedulerOptions{PollInterval: 10 * time.Millisecond})
+	defer scheduler.Close()
+
+	if _, err := scheduler.Schedule(context.Background(), time.Now(), "scope:1:students", []byte("hello")); err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		if msg.Channel != "scope:1:students" || string(msg.Payload) != "hello" {
+			t.Fatalf("unexpected message: %v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for scheduled event")
+	}
+}
+
+// blockingBroker holds every Publish until release is closed.
+type blockingBroker struct {
+	started chan struct{}
+	release chan struct{}
+	err     chan error
+}
+
+func (b *blockingBroker) Publish(ctx context.Context, _ string, _ []byte) error {
+	b.started <- struct{}{}
+	<-b.release
+	err := ctx.Err()
+	b.err <- err
+	return err
+}
+
+func (b *blockingBroker) Subscribe(context.Context, ...string) (sse.Subscription, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestSchedulerCloseFinishesClaimedEvents(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	broker := &blockingBroker{started: make(chan struct{}, 1), release: make(chan struct{}), err: make(chan error, 1)}
+	scheduler := NewScheduler(rdb, broker, SchedulerOptions{PollInterval: 10 * time.Millisecond})
+
+	if _, err := scheduler.Schedule(context.Background(), time.Now(), "scope:1:students", []byte("hello")); err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+	select {
+	case <-broker.started:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for dispatch")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		scheduler.Close()
+		close(closed)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(broker.release)
+
+	if err := <-broker.err; err != nil {
+		t.Fatalf("claimed event published with cancelled context: %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+}
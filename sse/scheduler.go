@@ -0,0 +1,55 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("scheduled event not found")
+	ErrNoScheduler      = errors.New("publisher has no scheduler")
+)
+
+// Scheduler publishes an already encoded payload on channel at a later time,
+// using PublishScheduled so sequenced payloads are numbered when sent.
+// Schedule returns an ID that can be passed to Cancel until the payload has
+// been published.
+type Scheduler interface {
+	Schedule(ctx context.Context, at time.Time, channel string, payload []byte) (string, error)
+	Cancel(ctx context.Context, id string) error
+}
+
+// PublishAt schedules event for at. A TTL counts from at, not from now.
+func (p *Publisher) PublishAt(ctx context.Context, at time.Time, channel string, event Event) (string, error) {
+	if p.opts.Scheduler == nil {
+		return "", ErrNoScheduler
+	}
+	if event.TTL > 0 && event.ExpiresAt.IsZero() {
+		event.ExpiresAt = at.Add(event.TTL)
+	}
+	payload, _, err := p.encode(ctx, channel, event)
+	if err != nil {
+		return "", err
+	}
+	if p.opts.Sequencer != nil {
+		payload = append([]byte(SequenceMarker+"\n"), payload...)
+	}
+	return p.opts.Scheduler.Schedule(ctx, at, channel, payload)
+}
+
+func (p *Publisher) PublishAfter(ctx context.Context, delay time.Duration, channel string, event Event) (string, error) {
+	return p.PublishAt(ctx, time.Now().Add(delay), channel, event)
+}
+
+func (p *Publisher) CancelScheduled(ctx context.Context, id string) error {
+	if p.opts.Scheduler == nil {
+		return ErrNoScheduler
+	}
+	return p.opts.Scheduler.Cancel(ctx, id)
+}
+
+// NewScheduleID returns a random identifier for scheduled events.
+func NewScheduleID() string {
+	return newEventID()
+}
@@ -0,0 +1,58 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+)
+
+// SequenceMarker prefixes broker payloads numbered by a Sequencer. It is
+// followed by the decimal sequence number, a newline and the payload. An
+// empty number marks a scheduled payload that is numbered when dispatched.
+const SequenceMarker = "\x00s"
+
+// SequencePayload frames payload with seq. Sequencer implementations use it
+// to build the message they publish.
+func SequencePayload(seq uint64, payload []byte) []byte {
+	out := make([]byte, 0, len(SequenceMarker)+21+len(payload))
+	out = append(out, SequenceMarker...)
+	out = strconv.AppendUint(out, seq, 10)
+	out = append(out, '\n')
+	return append(out, payload...)
+}
+
+// splitSequence strips a sequence frame, returning 0 for payloads without
+// one or not numbered yet.
+func splitSequence(payload []byte) (uint64, []byte, error) {
+	if !bytes.HasPrefix(payload, []byte(SequenceMarker)) {
+		return 0, payload, nil
+	}
+	num, rest, ok := bytes.Cut(payload[len(SequenceMarker):], []byte("\n"))
+	if !ok {
+		return 0, nil, errors.New("malformed sequence frame")
+	}
+	if len(num) == 0 {
+		return 0, rest, nil
+	}
+	seq, err := strconv.ParseUint(string(num), 10, 64)
+	if err != nil {
+		return 0, nil, errors.New("malformed sequence frame")
+	}
+	return seq, rest, nil
+}
+
+// PublishScheduled publishes a payload stored by a Scheduler. Payloads
+// scheduled by a Publisher with a Sequencer are numbered now when broker
+// implements Sequencer, and published without a number otherwise.
+func PublishScheduled(ctx context.Context, broker Broker, channel string, payload []byte) error {
+	unnumbered := []byte(SequenceMarker + "\n")
+	if !bytes.HasPrefix(payload, unnumbered) {
+		return broker.Publish(ctx, channel, payload)
+	}
+	payload = payload[len(unnumbered):]
+	if seq, ok := broker.(Sequencer); ok {
+		return seq.PublishSequenced(ctx, channel, payload)
+	}
+	return broker.Publish(ctx, channel, payload)
+}
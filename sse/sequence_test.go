@@ -0,0 +1,174 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// counterSequencer numbers payloads and publishes them on broker.
+type counterSequencer struct {
+	*testBroker
+	seqs map[string]uint64
+}
+
+func newCounterSequencer() *counterSequencer {
+	return &counterSequencer{testBroker: newTestBroker(), seqs: make(map[string]uint64)}
+}
+
+func (s *counterSequencer) PublishSequenced(ctx context.Context, channel string, payload []byte) error {
+	s.seqs[channel]++
+	return s.Publish(ctx, channel, SequencePayload(s.seqs[channel], payload))
+}
+
+func TestPublisherSequencesAfterClaim(t *testing.T) {
+	broker := newCounterSequencer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	pub := NewPublisherWithOptions(broker, PublisherOptions{Sequencer: broker, Deduplicator: mapDeduplicator{}})
+	for _, id := range []string{"a", "a", "b"} {
+		if err := pub.PublishEvent(context.Background(), "scope:1:students", Event{ID: id, EventType: "students.changed"}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	for want := uint64(1); want <= 2; want++ {
+		seq, payload, err := splitSequence((<-sub.Channel()).Payload)
+		if err != nil || seq != want {
+			t.Fatalf("unexpected sequence: %d, want %d (%v)", seq, want, err)
+		}
+		var evt Event
+		if err := json.Unmarshal(payload, &evt); err != nil || evt.Sequence != 0 {
+			t.Fatalf("unexpected payload: %s, %v", payload, err)
+		}
+	}
+	if broker.seqs["scope:1:students"] != 2 {
+		t.Fatalf("expected the suppressed duplicate not to use a number, got %d", broker.seqs["scope:1:students"])
+	}
+}
+
+func TestPublishScheduledNumbersAtDispatch(t *testing.T) {
+	broker := newCounterSequencer()
+	scheduler := &recordingScheduler{}
+	pub := NewPublisherWithOptions(broker, PublisherOptions{Sequencer: broker, Scheduler: scheduler})
+
+	if _, err := pub.PublishAfter(context.Background(), time.Hour, "scope:1:students", Event{EventType: "students.changed"}); err != nil {
+		t.Fatalf("schedule failed: %v", err)
+	}
+	if len(broker.seqs) != 0 {
+		t.Fatalf("expected no number at schedule time, got %v", broker.seqs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := broker.Subscribe(ctx, "scope:1:*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	if err := PublishScheduled(context.Background(), broker, "scope:1:students", scheduler.payload); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if seq, _, err := splitSequence((<-sub.Channel()).Payload); err != nil || seq != 1 {
+		t.Fatalf("unexpected sequence: %d, %v", seq, err)
+	}
+
+	// Without a Sequencer the frame is stripped.
+	if err := PublishScheduled(context.Background(), broker.testBroker, "scope:1:students", scheduler.payload); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if msg := <-sub.Channel(); bytes.HasPrefix(msg.Payload, []byte(SequenceMarker)) {
+		t.Fatalf("unexpected frame: %q", msg.Payload)
+	}
+}
+
+func TestSplitSequence(t *testing.T) {
+	if seq, payload, err := splitSequence(SequencePayload(42, []byte("x"))); err != nil || seq != 42 || string(payload) != "x" {
+		t.Fatalf("unexpected split: %d %q %v", seq, payload, err)
+	}
+	if seq, payload, err := splitSequence([]byte("x")); err != nil || seq != 0 || string(payload) != "x" {
+		t.Fatalf("unexpected split of unframed payload: %d %q %v", seq, payload, err)
+	}
+	for _, bad := range []string{SequenceMarker + "12", SequenceMarker + "x\nabc"} {
+		if _, _, err := splitSequence([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestHubEmitsGapOnSequenceHole(t *testing.T) {
+	var gaps []uint64
+	opts := Options{
+		Hooks: Hooks{OnSequenceGap: func(_ int64, _ string, missed uint64) { gaps = append(gaps, missed) }},
+	}
+	applyDefaultOptions(&opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := newHub(ctx, newTestBroker(), opts, 1, []string{"scope:1:*"})
+	c := hub.addClient(8, ConnInfo{})
+	defer hub.stop()
+
+	for _, seq := range []uint64{1, 2, 5} {
+		payload, _ := json.Marshal(Event{EventType: "students.changed"})
+		hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: SequencePayload(seq, payload)})
+	}
+	payload, _ := json.Marshal(Event{EventType: "plans.changed", Sequence: 9})
+	hub.broadcast(BrokerMsg{Channel: "scope:1:plans", Payload: payload})
+
+	if len(gaps) != 1 || gaps[0] != 2 {
+		t.Fatalf("unexpected gaps: %v", gaps)
+	}
+
+	var dropped []int
+	var ids []string
+	for {
+		msg, n, ok := c.next()
+		dropped = append(dropped, n)
+		if !ok {
+			break
+		}
+		ids = append(ids, msg.id)
+	}
+	if len(dropped) != 5 || dropped[0] != 2 {
+		t.Fatalf("expected gap to be reported with the next delivery, got %v", dropped)
+	}
+	want := []string{"scope:1:students:1", "scope:1:students:2", "scope:1:students:5", "scope:1:plans:9"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected event ids: %v", ids)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSSE(&buf, ids[0], "students.changed", []byte("{}")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if buf.String() != "id: scope:1:students:1\nevent: students.changed\ndata: {}\n\n" {
+		t.Fatalf("unexpected frame: %q", buf.String())
+	}
+}
+
+func TestHubEmitsGapForBrokerDrops(t *testing.T) {
+	opts := Options{}
+	applyDefaultOptions(&opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := newHub(ctx, newTestBroker(), opts, 1, []string{"scope:1:*"})
+	c := hub.addClient(8, ConnInfo{})
+	defer hub.stop()
+
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: []byte(`{"event_type":"x"}`), Dropped: 3})
+
+	if _, dropped, ok := c.next(); !ok || dropped != 3 {
+		t.Fatalf("expected gap of 3 with the next delivery, got %d, %v", dropped, ok)
+	}
+}
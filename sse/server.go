@@ -12,6 +12,8 @@ type Server struct {
 	publisher *Publisher
 	hubs      *hubManager
 	handler   http.Handler
+
+	publishHandler http.Handler
 }
 
 func NewServer(broker Broker, options Options) (*Server, error) {
@@ -27,16 +29,39 @@ func NewServer(broker Broker, options Options) (*Server, error) {
 		return nil, errors.New("channel router cannot be nil")
 	}
 
+	if options.Keyring != nil {
+		if err := options.Keyring.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := options.CORS.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateOrigins(options.AllowedOrigins); err != nil {
+		return nil, err
+	}
+
 	applyDefaultOptions(&options)
 
 	s := &Server{
-		broker:    broker,
-		opts:      options,
-		publisher: NewPublisher(broker),
+		broker: broker,
+		opts:   options,
+		publisher: NewPublisherWithOptions(broker, PublisherOptions{
+			Codec:             options.Codec,
+			Scheduler:         options.Scheduler,
+			Deduplicator:      options.Deduplicator,
+			Sequencer:         options.Sequencer,
+			Compression:       options.Compression,
+			Keyring:           options.Keyring,
+			ChannelValidation: options.ChannelValidation,
+			OnError:           options.Hooks.OnError,
+		}),
 	}
 
 	s.hubs = newHubManager(options.Context, broker, options)
-	s.handler = newHandler(s.hubs, options)
+	s.handler = newHandler(s.hubs, options, HandlerOptions{})
+	s.publishHandler = newPublishHandler(s.publisher, options)
 
 	return s, nil
 }
@@ -45,6 +70,16 @@ func (s *Server) Handler() http.Handler {
 	return s.handler
 }
 
+// HandlerWithOptions returns a stream handler sharing the server's hubs,
+// customized per route.
+func (s *Server) HandlerWithOptions(options HandlerOptions) http.Handler {
+	return newHandler(s.hubs, s.opts, options)
+}
+
+func (s *Server) PublishHandler() http.Handler {
+	return s.publishHandler
+}
+
 func (s *Server) Publisher() *Publisher {
 	return s.publisher
 }
@@ -56,6 +56,9 @@ func TestNewServerAppliesDefaults(t *testing.T) {
 	if server.opts.EventEncoder == nil {
 		t.Fatal("expected event encoder to be set")
 	}
+	if server.opts.DedupWindow != 1024 {
+		t.Fatalf("unexpected dedup window: %d", server.opts.DedupWindow)
+	}
 }
 
 func TestEventNamePrefixApplied(t *testing.T) {
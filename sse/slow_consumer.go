@@ -0,0 +1,70 @@
+package sse
+
+import (
+	"sort"
+	"time"
+)
+
+type SlowConsumerPolicy struct {
+	// MaxWriteLatency disconnects a client whose p95 write+flush latency over
+	// the last LatencyWindow writes exceeds it. Zero disables the check.
+	MaxWriteLatency time.Duration
+	// MaxLag disconnects a client whose oldest queued event is older than it.
+	// Zero disables the check.
+	MaxLag time.Duration
+	// LatencyWindow is the number of recent writes used for the p95.
+	LatencyWindow int
+}
+
+type SlowConsumerStats struct {
+	Reason          string
+	WriteLatencyP95 time.Duration
+	Lag             time.Duration
+	Samples         int
+}
+
+const (
+	SlowConsumerWriteLatency = "write latency"
+	SlowConsumerLag          = "lag"
+)
+
+const minLatencySamples = 5
+
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+	n       int
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	if size < 1 {
+		size = 1
+	}
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+func (lw *latencyWindow) add(d time.Duration) {
+	lw.samples[lw.next] = d
+	lw.next = (lw.next + 1) % len(lw.samples)
+	if lw.n < len(lw.samples) {
+		lw.n++
+	}
+}
+
+func (lw *latencyWindow) p95() time.Duration {
+	if lw.n == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, lw.n)
+	copy(sorted, lw.samples[:lw.n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := (lw.n*95+99)/100 - 1
+	return sorted[idx]
+}
+
+func (p SlowConsumerPolicy) exceedsWriteLatency(lw *latencyWindow) bool {
+	if p.MaxWriteLatency <= 0 || lw.n < minLatencySamples && lw.n < len(lw.samples) {
+		return false
+	}
+	return lw.p95() > p.MaxWriteLatency
+}
@@ -0,0 +1,80 @@
+package sse
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyWindowP95(t *testing.T) {
+	lw := newLatencyWindow(20)
+	for i := 1; i <= 20; i++ {
+		lw.add(time.Duration(i) * time.Millisecond)
+	}
+	if got := lw.p95(); got != 19*time.Millisecond {
+		t.Fatalf("unexpected p95: %v", got)
+	}
+
+	lw.add(100 * time.Millisecond)
+	if got := lw.p95(); got != 20*time.Millisecond {
+		t.Fatalf("unexpected p95 after wrap: %v", got)
+	}
+}
+
+func TestSlowConsumerPolicyWriteLatency(t *testing.T) {
+	policy := SlowConsumerPolicy{MaxWriteLatency: 10 * time.Millisecond}
+	lw := newLatencyWindow(20)
+
+	for i := 0; i < minLatencySamples-1; i++ {
+		lw.add(time.Second)
+	}
+	if policy.exceedsWriteLatency(lw) {
+		t.Fatal("expected too few samples to be ignored")
+	}
+
+	lw.add(time.Second)
+	if !policy.exceedsWriteLatency(lw) {
+		t.Fatal("expected write latency to exceed threshold")
+	}
+
+	if (SlowConsumerPolicy{}).exceedsWriteLatency(lw) {
+		t.Fatal("expected zero threshold to disable the check")
+	}
+}
+
+func TestHubDisconnectsLaggingClient(t *testing.T) {
+	var stats []SlowConsumerStats
+	var slowConn string
+	opts := Options{
+		SlowConsumer: SlowConsumerPolicy{MaxLag: 10 * time.Millisecond},
+		Hooks: Hooks{
+			OnSlowConsumer: func(_ int64, s SlowConsumerStats) { stats = append(stats, s) },
+			OnConnSlow:     func(info ConnInfo, _ SlowConsumerStats) { slowConn = info.ID },
+		},
+	}
+
+	applyDefaultOptions(&opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := newHub(ctx, newTestBroker(), opts, 1, []string{"scope:1:*"})
+	c := hub.addClient(4, ConnInfo{ID: "c1"})
+	defer hub.stop()
+
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: []byte("a")})
+	time.Sleep(20 * time.Millisecond)
+	hub.broadcast(BrokerMsg{Channel: "scope:1:students", Payload: []byte("b")})
+
+	select {
+	case <-c.done:
+	default:
+		t.Fatal("expected lagging client to be disconnected")
+	}
+	if len(stats) != 1 || stats[0].Reason != SlowConsumerLag || stats[0].Lag < 10*time.Millisecond {
+		t.Fatalf("unexpected slow consumer stats: %+v", stats)
+	}
+	if slowConn != "c1" {
+		t.Fatalf("expected OnConnSlow for c1, got %q", slowConn)
+	}
+}
@@ -0,0 +1,66 @@
+package sse
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"time"
+)
+
+type streamWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+	latency *latencyWindow
+	gz      *gzip.Writer
+}
+
+func newStreamWriter(w http.ResponseWriter, opts Options, compress bool) *streamWriter {
+	sw := &streamWriter{
+		w:       w,
+		rc:      http.NewResponseController(w),
+		timeout: opts.WriteTimeout,
+		latency: newLatencyWindow(opts.SlowConsumer.LatencyWindow),
+	}
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.Header().Del("Content-Length")
+		sw.gz = gzip.NewWriter(w)
+	}
+	return sw
+}
+
+// send runs write under the configured write deadline, flushes, and records
+// how long the write and flush took.
+func (sw *streamWriter) send(write func(w io.Writer) error) error {
+	if sw.timeout > 0 {
+		// Writers that don't support deadlines still stream, just unbounded.
+		_ = sw.rc.SetWriteDeadline(time.Now().Add(sw.timeout))
+		defer func() { _ = sw.rc.SetWriteDeadline(time.Time{}) }()
+	}
+
+	start := time.Now()
+	var err error
+	if sw.gz != nil {
+		// Flushing the compressor emits a complete deflate block, so the
+		// client can decode every event as soon as it arrives.
+		if err = write(sw.gz); err == nil {
+			err = sw.gz.Flush()
+		}
+	} else {
+		err = write(sw.w)
+	}
+	if err == nil {
+		err = sw.rc.Flush()
+	}
+	sw.latency.add(time.Since(start))
+	return err
+}
+
+// close terminates the gzip stream, if any.
+func (sw *streamWriter) close() {
+	if sw.gz != nil {
+		_ = sw.gz.Close()
+	}
+}
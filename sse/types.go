@@ -1,8 +1,32 @@
 package sse
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Event struct {
 	EventType string          `json:"event_type"`
 	Data      json.RawMessage `json:"data,omitempty"`
+
+	ID              string    `json:"id,omitempty"`
+	Source          string    `json:"source,omitempty"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time,omitzero"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+
+	// ExpiresAt is the deadline after which the event is discarded instead
+	// of delivered. TTL, when set, is converted to ExpiresAt on publish,
+	// counting from the scheduled time for PublishAt.
+	ExpiresAt time.Time     `json:"expires_at,omitzero"`
+	TTL       time.Duration `json:"-"`
+
+	// Sequence lets hubs detect lost messages for publishers that number
+	// events themselves. A Publisher with a Sequencer leaves it zero and
+	// numbers the broker message instead.
+	Sequence uint64 `json:"seq,omitempty"`
+}
+
+func (e Event) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
 }